@@ -1,13 +1,31 @@
 package restconf
 
 import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
 	"errors"
 	"io/ioutil"
+	"math/big"
+	mrand "math/rand"
 	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/tidwall/gjson"
 	"gopkg.in/h2non/gock.v1"
 )
 
@@ -23,6 +41,28 @@ func testClient() *Client {
 	return client
 }
 
+// testCertificate creates a self-signed certificate.
+func testCertificate(t *testing.T) tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "client"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
 // ErrReader implements the io.Reader interface and fails on Read.
 type ErrReader struct{}
 
@@ -31,11 +71,200 @@ func (r ErrReader) Read(buf []byte) (int, error) {
 	return 0, errors.New("fail")
 }
 
+// testLogger records logged messages.
+type testLogger struct {
+	messages []string
+	// key/value pairs of the last error
+	errorFields []interface{}
+}
+
+func (l *testLogger) Debug(msg string, keysAndValues ...interface{}) {
+	l.messages = append(l.messages, "DEBUG "+msg)
+}
+
+func (l *testLogger) Warn(msg string, keysAndValues ...interface{}) {
+	l.messages = append(l.messages, "WARN "+msg)
+}
+
+func (l *testLogger) Error(msg string, keysAndValues ...interface{}) {
+	l.messages = append(l.messages, "ERROR "+msg)
+	l.errorFields = keysAndValues
+}
+
 // TestNewClient tests the NewClient function.
 func TestNewClient(t *testing.T) {
 	client, _ := NewClient(testURL, "usr", "pwd", true, RequestTimeout(120), MaxRetries(0))
 	assert.Equal(t, client.HttpClient.Timeout, 120*time.Second)
 	assert.Equal(t, client.MaxRetries, 0)
+	assert.NotNil(t, client.HttpClient.Jar)
+
+	client, _ = NewClient(testURL, "usr", "pwd", true, WithoutCookies())
+	assert.Nil(t, client.HttpClient.Jar)
+}
+
+// TestClientCertificate tests the WithClientCertificate modifier.
+func TestClientCertificate(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(204)
+	}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+	server.StartTLS()
+	defer server.Close()
+
+	client, _ := NewClient(server.URL, "usr", "pwd", true, SkipDiscovery("/restconf", false), MaxRetries(0), WithClientCertificate(testCertificate(t)))
+	_, err := client.GetData("url")
+	assert.NoError(t, err)
+
+	// Without client certificate
+	client, _ = NewClient(server.URL, "usr", "pwd", true, SkipDiscovery("/restconf", false), MaxRetries(0))
+	_, err = client.GetData("url")
+	assert.Error(t, err)
+
+	// Missing certificate files
+	_, err = NewClient(server.URL, "usr", "pwd", true, WithClientCertFiles(filepath.Join(t.TempDir(), "cert.pem"), filepath.Join(t.TempDir(), "key.pem")))
+	assert.Error(t, err)
+}
+
+// TestInsecure tests that the insecure parameter of NewClient controls certificate verification.
+func TestInsecure(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(204)
+	}))
+	defer server.Close()
+
+	client, _ := NewClient(server.URL, "usr", "pwd", false, SkipDiscovery("/restconf", false), MaxRetries(0))
+	assert.False(t, client.HttpClient.Transport.(*http.Transport).TLSClientConfig.InsecureSkipVerify)
+	_, err := client.GetData("url")
+	assert.Error(t, err)
+
+	client, _ = NewClient(server.URL, "usr", "pwd", true, SkipDiscovery("/restconf", false), MaxRetries(0))
+	assert.True(t, client.HttpClient.Transport.(*http.Transport).TLSClientConfig.InsecureSkipVerify)
+	_, err = client.GetData("url")
+	assert.NoError(t, err)
+}
+
+type testRoundTripper struct {
+	requests int
+}
+
+func (rt *testRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	rt.requests++
+	return &http.Response{StatusCode: 204, Header: http.Header{}, Body: ioutil.NopCloser(strings.NewReader("")), Request: req}, nil
+}
+
+// TestWithHTTPClient tests the WithHTTPClient and WithTransport options.
+func TestWithHTTPClient(t *testing.T) {
+	rt := &testRoundTripper{}
+	client, _ := NewClient(testURL, "usr", "pwd", true, SkipDiscovery("/restconf", false), WithTransport(rt))
+	_, err := client.GetData("url")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, rt.requests)
+	assert.NotNil(t, client.HttpClient.Jar)
+
+	httpClient := &http.Client{Transport: rt}
+	client, _ = NewClient(testURL, "usr", "pwd", true, SkipDiscovery("/restconf", false), WithHTTPClient(httpClient))
+	_, err = client.GetData("url")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, rt.requests)
+	assert.NotNil(t, client.HttpClient.Jar)
+	assert.Nil(t, httpClient.Jar)
+
+	_, err = NewClient(testURL, "usr", "pwd", true, WithTransport(rt), WithProxy("http://proxy:3128"))
+	assert.Error(t, err)
+}
+
+// TestWithProxy tests the WithProxy option.
+func TestWithProxy(t *testing.T) {
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Proxied-URL", r.URL.String())
+		w.WriteHeader(204)
+	}))
+	defer proxy.Close()
+
+	client, err := NewClient("http://10.0.0.1", "usr", "pwd", true, SkipDiscovery("/restconf", false), MaxRetries(0), WithProxy(proxy.URL))
+	assert.NoError(t, err)
+	res, err := client.GetData("url")
+	assert.NoError(t, err)
+	assert.Equal(t, "http://10.0.0.1/restconf/data/url", res.Header.Get("X-Proxied-URL"))
+
+	_, err = NewClient(testURL, "usr", "pwd", true, WithProxy("proxy.example.com:3128"))
+	assert.ErrorContains(t, err, "Invalid proxy URL")
+	_, err = NewClient(testURL, "usr", "pwd", true, WithProxy("http://%zz"))
+	assert.ErrorContains(t, err, "Invalid proxy URL")
+}
+
+// TestTransportOptions tests the WithMaxIdleConnsPerHost, WithIdleConnTimeout and WithHTTP2 options.
+func TestTransportOptions(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Proto", r.Proto)
+		w.WriteHeader(204)
+	}))
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	defer server.Close()
+
+	client, _ := NewClient(server.URL, "usr", "pwd", true, SkipDiscovery("/restconf", false), MaxRetries(0),
+		WithMaxIdleConnsPerHost(100), WithIdleConnTimeout(90*time.Second), WithHTTP2(true))
+	tr := client.HttpClient.Transport.(*http.Transport)
+	assert.Equal(t, 100, tr.MaxIdleConnsPerHost)
+	assert.Equal(t, 90*time.Second, tr.IdleConnTimeout)
+	res, err := client.GetData("url")
+	assert.NoError(t, err)
+	assert.Equal(t, "HTTP/2.0", res.Header.Get("X-Proto"))
+
+	client, _ = NewClient(server.URL, "usr", "pwd", true, SkipDiscovery("/restconf", false), MaxRetries(0))
+	res, _ = client.GetData("url")
+	assert.Equal(t, "HTTP/1.1", res.Header.Get("X-Proto"))
+}
+
+// TestMinTLSVersion tests the WithMinTLSVersion modifier.
+func TestMinTLSVersion(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(204)
+	}))
+	server.TLS = &tls.Config{MinVersion: tls.VersionTLS10, MaxVersion: tls.VersionTLS10}
+	server.StartTLS()
+	defer server.Close()
+
+	client, _ := NewClient(server.URL, "usr", "pwd", true, SkipDiscovery("/restconf", false), MaxRetries(0))
+	assert.Equal(t, uint16(tls.VersionTLS12), client.HttpClient.Transport.(*http.Transport).TLSClientConfig.MinVersion)
+	_, err := client.GetData("url")
+	assert.Error(t, err)
+
+	client, _ = NewClient(server.URL, "usr", "pwd", true, WithMinTLSVersion(tls.VersionTLS13), WithCipherSuites(tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384))
+	config := client.HttpClient.Transport.(*http.Transport).TLSClientConfig
+	assert.Equal(t, uint16(tls.VersionTLS13), config.MinVersion)
+	assert.Equal(t, []uint16{tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384}, config.CipherSuites)
+}
+
+// TestRootCAs tests the verification of device certificates and the WithRootCAs modifier.
+func TestRootCAs(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(204)
+	}))
+	defer server.Close()
+
+	// Untrusted certificate
+	client, _ := NewClient(server.URL, "usr", "pwd", false, SkipDiscovery("/restconf", false), MaxRetries(0))
+	_, err := client.GetData("url")
+	assert.Error(t, err)
+
+	pool := x509.NewCertPool()
+	pool.AddCert(server.Certificate())
+	client, _ = NewClient(server.URL, "usr", "pwd", false, SkipDiscovery("/restconf", false), MaxRetries(0), WithRootCAs(pool))
+	_, err = client.GetData("url")
+	assert.NoError(t, err)
+
+	// CA certificate file
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	ioutil.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}), 0644)
+	client, err = NewClient(server.URL, "usr", "pwd", false, SkipDiscovery("/restconf", false), MaxRetries(0), WithCACertFile(caFile))
+	assert.NoError(t, err)
+	_, err = client.GetData("url")
+	assert.NoError(t, err)
+
+	_, err = NewClient(server.URL, "usr", "pwd", false, WithCACertFile(filepath.Join(t.TempDir(), "missing.pem")))
+	assert.Error(t, err)
 }
 
 // TestDiscoverRestconfEndpoint tests the Client::discoverRestconfEndpoint method.
@@ -55,6 +284,36 @@ func TestDiscoverCapabilities(t *testing.T) {
 	assert.Equal(t, client.YangPatchCapability, true)
 }
 
+// TestWithDefaultsSupportedModes tests the Client::WithDefaultsSupportedModes method.
+func TestWithDefaultsSupportedModes(t *testing.T) {
+	client, _ := NewClient(testURL, "usr", "pwd", true, WithStaticCapabilities("/restconf", []string{
+		"urn:ietf:params:restconf:capability:defaults:1.0?basic-mode=explicit&also-supported=report-all,report-all-tagged",
+	}))
+	assert.Equal(t, []string{"explicit", "report-all", "report-all-tagged"}, client.WithDefaultsSupportedModes())
+
+	client, _ = NewClient(testURL, "usr", "pwd", true, WithStaticCapabilities("/restconf", []string{}))
+	assert.Empty(t, client.WithDefaultsSupportedModes())
+}
+
+// TestWithDefaults tests the WithDefaults request modifier.
+func TestWithDefaults(t *testing.T) {
+	defer gock.Off()
+	client, _ := NewClient(testURL, "usr", "pwd", true, MaxRetries(0), WithStaticCapabilities("/restconf", []string{
+		"urn:ietf:params:restconf:capability:defaults:1.0?basic-mode=explicit&also-supported=report-all",
+	}))
+	gock.InterceptClient(client.HttpClient)
+
+	gock.New(testURL).Get("/restconf/data/url").MatchParam("with-defaults", "report-all").Reply(200)
+	_, err := client.GetData("url", WithDefaults(DefaultsReportAll))
+	assert.NoError(t, err)
+
+	_, err = client.GetData("url", WithDefaults(DefaultsTrim))
+	assert.ErrorContains(t, err, `With-defaults mode "trim" not supported`)
+
+	_, err = client.GetData("url", WithDefaults("all"))
+	assert.ErrorContains(t, err, `Invalid with-defaults mode "all"`)
+}
+
 // TestClientGet tests the Client::GetData method.
 func TestClientGetData(t *testing.T) {
 	defer gock.Off()
@@ -88,6 +347,76 @@ func TestClientGetData(t *testing.T) {
 	assert.Error(t, err)
 }
 
+// TestClientGetJSON tests the Client::GetJSON method.
+func TestClientGetJSON(t *testing.T) {
+	defer gock.Off()
+	client := testClient()
+
+	// Success
+	gock.New(testURL).Get("/restconf/data/Cisco-IOS-XE-native:native/hostname").
+		Reply(200).
+		BodyString(`{"Cisco-IOS-XE-native:hostname":"R1"}`)
+	res, err := client.GetJSON("Cisco-IOS-XE-native:native/hostname")
+	assert.NoError(t, err)
+	assert.Equal(t, "R1", res.Get("Cisco-IOS-XE-native:hostname").String())
+
+	// Invalid HTTP status code
+	gock.New(testURL).Get("/restconf/data/Cisco-IOS-XE-native:native/hostname").Reply(404)
+	_, err = client.GetJSON("Cisco-IOS-XE-native:native/hostname")
+	assert.Error(t, err)
+}
+
+// TestClientResponseHeader tests the response headers of Res.
+func TestClientResponseHeader(t *testing.T) {
+	defer gock.Off()
+	client := testClient()
+
+	gock.New(testURL).Post("/restconf/data/url").
+		Reply(201).
+		SetHeader("Location", testURL+"/restconf/data/url/entry=1").
+		SetHeader("Cache-Control", "no-cache")
+	res, err := client.PostData("url", "{}")
+	assert.NoError(t, err)
+	assert.Equal(t, testURL+"/restconf/data/url/entry=1", res.Header.Get("Location"))
+	assert.Equal(t, "no-cache", res.Header.Get("Cache-Control"))
+}
+
+// TestClientLocation tests the Location header of Res.
+func TestClientLocation(t *testing.T) {
+	defer gock.Off()
+	client := testClient()
+
+	gock.New(testURL).Post("/restconf/data/Cisco-IOS-XE-native:native").
+		Reply(201).
+		SetHeader("Location", testURL+"/restconf/data/Cisco-IOS-XE-native:native/username=test-user")
+	res, err := client.PostData("Cisco-IOS-XE-native:native", "{}")
+	assert.NoError(t, err)
+	assert.Equal(t, testURL+"/restconf/data/Cisco-IOS-XE-native:native/username=test-user", res.Location)
+	assert.Equal(t, "Cisco-IOS-XE-native:native/username=test-user", res.LocationPath())
+
+	// Relative location
+	gock.New(testURL).Post("/restconf/data/Cisco-IOS-XE-native:native").
+		Reply(201).
+		SetHeader("Location", "/restconf/data/Cisco-IOS-XE-native:native/username=test-user")
+	res, _ = client.PostData("Cisco-IOS-XE-native:native", "{}")
+	assert.Equal(t, "Cisco-IOS-XE-native:native/username=test-user", res.LocationPath())
+
+	// Location outside of data resource
+	gock.New(testURL).Post("/restconf/data/Cisco-IOS-XE-native:native").
+		Reply(201).
+		SetHeader("Location", testURL+"/other")
+	res, _ = client.PostData("Cisco-IOS-XE-native:native", "{}")
+	assert.Equal(t, "", res.LocationPath())
+
+	// Location of a datastore resource
+	gock.New(testURL).Post("/restconf/data/Cisco-IOS-XE-native:native").
+		Reply(201).
+		SetHeader("Location", "/restconf/ds/ietf-datastores:candidate/Cisco-IOS-XE-native:native/username=test-user")
+	res, _ = client.PostData("Cisco-IOS-XE-native:native", "{}")
+	assert.Equal(t, "/restconf/ds/ietf-datastores:candidate/Cisco-IOS-XE-native:native/username=test-user", res.Location)
+	assert.Equal(t, "", res.LocationPath())
+}
+
 // TestClientPostData tests the Client::PostData method.
 func TestClientPostData(t *testing.T) {
 	defer gock.Off()
@@ -122,6 +451,34 @@ func TestClientPostData(t *testing.T) {
 	assert.Error(t, err)
 }
 
+// TestClientAction tests the Client::Action method.
+func TestClientAction(t *testing.T) {
+	defer gock.Off()
+	client := testClient()
+
+	// Success
+	var body string
+	gock.New(testURL).Post("/restconf/data/example-server-farm:server=apache-1/example-server-farm:reset").
+		AddMatcher(func(req *http.Request, _ *gock.Request) (bool, error) {
+			b, err := ioutil.ReadAll(req.Body)
+			body = string(b)
+			return true, err
+		}).
+		Reply(200).
+		BodyString(`{"example-server-farm:output":{"reset-finished-at":"2022-03-01T00:00:00Z"}}`)
+	res, err := client.Action("example-server-farm:server=apache-1", "example-server-farm:reset", `{"example-server-farm:input":{"reset-at":"2022-03-01T00:00:00Z"}}`)
+	assert.NoError(t, err)
+	assert.True(t, gock.IsDone())
+	assert.Equal(t, `{"example-server-farm:input":{"reset-at":"2022-03-01T00:00:00Z"}}`, body)
+	assert.Equal(t, "2022-03-01T00:00:00Z", res.Res.Get("example-server-farm:output.reset-finished-at").String())
+
+	// Invalid HTTP status code
+	gock.New(testURL).Post("/restconf/data/example-server-farm:server=apache-1/example-server-farm:reset").Reply(400)
+	res, err = client.Action("example-server-farm:server=apache-1", "example-server-farm:reset", "")
+	assert.Error(t, err)
+	assert.Equal(t, 400, res.StatusCode)
+}
+
 // TestBackoff tests the Client::Backoff method.
 func TestBackoff(t *testing.T) {
 	client := testClient()
@@ -132,3 +489,1664 @@ func TestBackoff(t *testing.T) {
 	duration := time.Since(start)
 	assert.GreaterOrEqual(t, duration.Seconds(), float64(client.BackoffMinDelay))
 }
+
+// TestHeartbeatTimeout tests the WithHeartbeatTimeout modifier and the detection of silent streams.
+func TestHeartbeatTimeout(t *testing.T) {
+	client, _ := NewClient(testURL, "usr", "pwd", true, WithHeartbeatTimeout(50*time.Millisecond))
+	assert.Equal(t, 50*time.Millisecond, client.SubscriptionHeartbeatTimeout)
+
+	h, ctx, cancel := newHeartbeat(context.Background(), client.SubscriptionHeartbeatTimeout)
+	defer cancel()
+	time.Sleep(20 * time.Millisecond)
+	assert.True(t, h.pause())
+	time.Sleep(50 * time.Millisecond)
+	assert.NoError(t, ctx.Err())
+	h.resume()
+	select {
+	case <-ctx.Done():
+	case <-time.After(time.Second):
+		t.Fatal("heartbeat timeout did not expire")
+	}
+	assert.True(t, h.expired())
+	assert.False(t, h.pause())
+
+	// Disabled timeout
+	h, ctx, cancel = newHeartbeat(context.Background(), 0)
+	defer cancel()
+	assert.True(t, h.pause())
+	h.resume()
+	assert.NoError(t, ctx.Err())
+	assert.False(t, h.expired())
+}
+
+// TestMaxCumulativeBackoff tests the WithMaxCumulativeBackoff modifier.
+func TestMaxCumulativeBackoff(t *testing.T) {
+	defer gock.Off()
+	client := testClient()
+	client.MaxRetries = 3
+	WithMaxCumulativeBackoff(500 * time.Millisecond)(client)
+
+	gock.New(testURL).Get("/restconf/data/url").
+		Reply(503).
+		BodyString(`{"ietf-restconf:errors":{"error":[{"error-type":"transport","error-tag":"resource-denied"}]}}`)
+	start := time.Now()
+	_, err := client.GetData("url")
+	assert.Error(t, err)
+	assert.Less(t, time.Since(start), time.Duration(client.BackoffMinDelay)*time.Second)
+}
+
+// TestCheckStrictJSON tests the checkStrictJSON function.
+func TestCheckStrictJSON(t *testing.T) {
+	assert.NoError(t, checkStrictJSON([]byte(`{"a":{"b":[1,{"c":true}]},"d":null}`)))
+	assert.Error(t, checkStrictJSON([]byte(`{"a":1,"a":2}`)))
+	assert.Error(t, checkStrictJSON([]byte(`{"a":{"b":1,"b":2}}`)))
+	assert.Error(t, checkStrictJSON([]byte(`{"a":1`)))
+	assert.Error(t, checkStrictJSON([]byte(`{"a":1}{}`)))
+}
+
+// TestDefaultGetMods tests the WithDefaultGetMods modifier.
+func TestDefaultGetMods(t *testing.T) {
+	defer gock.Off()
+	client := testClient()
+	WithDefaultGetMods(Query("content", "config"))(client)
+
+	gock.New(testURL).Get("/restconf/data/url").MatchParam("content", "config").Reply(200)
+	_, err := client.GetData("url")
+	assert.NoError(t, err)
+
+	req := client.NewReq("PUT", "/data/url", nil)
+	assert.Equal(t, "", req.HttpReq.URL.Query().Get("content"))
+}
+
+// TestDefaultQuery tests the WithDefaultQuery option.
+func TestDefaultQuery(t *testing.T) {
+	client := testClient()
+	WithDefaultQuery("content", "config")(client)
+	WithDefaultQuery("with-defaults", "report-all-tagged")(client)
+
+	req := client.NewReq("GET", "/data/url", nil)
+	assert.Equal(t, "config", req.HttpReq.URL.Query().Get("content"))
+	assert.Equal(t, "report-all-tagged", req.HttpReq.URL.Query().Get("with-defaults"))
+
+	req = client.NewReq("GET", "/data/url", nil, Query("content", "nonconfig"))
+	assert.Equal(t, []string{"nonconfig"}, req.HttpReq.URL.Query()["content"])
+	assert.Equal(t, "report-all-tagged", req.HttpReq.URL.Query().Get("with-defaults"))
+
+	req = client.NewReq("PUT", "/data/url", nil, NoDefaultQuery())
+	assert.Equal(t, "", req.HttpReq.URL.RawQuery)
+}
+
+// TestDefaultsInternalRequests tests that default modifiers and query parameters are not applied to internal requests.
+func TestDefaultsInternalRequests(t *testing.T) {
+	var mutex sync.Mutex
+	queries := map[string]string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		queries[r.URL.Path] = r.URL.RawQuery
+		mutex.Unlock()
+		switch r.URL.Path {
+		case "/.well-known/host-meta":
+			w.Write([]byte(`<XRD xmlns='http://docs.oasis-open.org/ns/xri/xrd-1.0'><Link rel='restconf' href='/restconf'/></XRD>`))
+		case "/restconf/data/ietf-restconf-monitoring:restconf-state/capabilities":
+			w.Write([]byte(`{"ietf-restconf-monitoring:capabilities":{"capability":["urn:ietf:params:restconf:capability:defaults:1.0?basic-mode=explicit"]}}`))
+		case "/restconf/data/ietf-yang-library:yang-library/module-set":
+			w.Write([]byte(`{"ietf-yang-library:module-set":[{"name":"all","module":[{"name":"a","revision":"2024-01-01"}]}]}`))
+		default:
+			w.WriteHeader(204)
+		}
+	}))
+	defer server.Close()
+
+	client, _ := NewClient(server.URL, "usr", "pwd", true, MaxRetries(0),
+		WithDefaultGetMods(Query("content", "config")), WithDefaultQuery("content", "config"))
+	_, err := client.GetData("url")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"explicit"}, client.WithDefaultsSupportedModes())
+	modules, err := client.ModuleList()
+	assert.NoError(t, err)
+	assert.Equal(t, "a", modules[0].Name)
+
+	assert.Equal(t, "content=config", queries["/restconf/data/url"])
+	assert.Equal(t, "", queries["/restconf/data/ietf-restconf-monitoring:restconf-state/capabilities"])
+	assert.Equal(t, "", queries["/restconf/data/ietf-yang-library:yang-library/module-set"])
+}
+
+// TestResolvedBaseURL tests the Client::ResolvedBaseURL method.
+func TestResolvedBaseURL(t *testing.T) {
+	defer gock.Off()
+	client := testClient()
+	assert.Equal(t, "", client.ResolvedBaseURL())
+
+	gock.New(testURL).Get("/restconf/data/url").Reply(200)
+	client.GetData("url")
+	assert.Equal(t, testURL+"/restconf", client.ResolvedBaseURL())
+}
+
+// TestSchemaContentID tests the Client::SchemaContentID method.
+func TestSchemaContentID(t *testing.T) {
+	defer gock.Off()
+	client := testClient()
+
+	gock.New(testURL).Get("/restconf/data/ietf-yang-library:yang-library/content-id").Reply(404)
+	gock.New(testURL).Get("/restconf/data/ietf-yang-library:modules-state/module-set-id").Reply(200).BodyString(`{"ietf-yang-library:module-set-id":"abc"}`)
+	id, err := client.SchemaContentID()
+	assert.NoError(t, err)
+	assert.Equal(t, "abc", id)
+
+	// Changed after a software upgrade
+	gock.New(testURL).Get("/restconf/data/ietf-yang-library:yang-library/content-id").Reply(200).BodyString(`{"ietf-yang-library:content-id":"def"}`)
+	id, err = client.SchemaContentID()
+	assert.NoError(t, err)
+	assert.Equal(t, "def", id)
+}
+
+// TestClientDoRawBytes tests the Client::DoRawBytes method.
+func TestClientDoRawBytes(t *testing.T) {
+	defer gock.Off()
+	client := testClient()
+
+	gock.New(testURL).Get("/restconf/data/url").Reply(404).BodyString(`{"errors":{"error":[{"error-tag":"invalid-value"}]}}`)
+	status, body, _, err := client.DoRawBytes("GET", "/data/url", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 404, status)
+	assert.Equal(t, `{"errors":{"error":[{"error-tag":"invalid-value"}]}}`, string(body))
+
+	gock.New(testURL).Get("/restconf/data/url").ReplyError(errors.New("fail"))
+	_, _, _, err = client.DoRawBytes("GET", "/data/url", nil)
+	assert.Error(t, err)
+}
+
+// TestPathRewriter tests the WithPathRewriter modifier.
+func TestPathRewriter(t *testing.T) {
+	client, _ := NewClient(testURL, "usr", "pwd", true, SkipDiscovery("/restconf", false), WithPathRewriter(func(method, path string) string {
+		return strings.Replace(path, "old-module:", "new-module:", 1)
+	}))
+	req := client.NewReq("GET", "/data/old-module:container", nil)
+	assert.Equal(t, "/restconf/data/new-module:container", req.HttpReq.URL.Path)
+}
+
+// TestEncodingXML tests the WithEncoding modifier with XML encoding.
+func TestEncodingXML(t *testing.T) {
+	defer gock.Off()
+	client := testClient()
+	WithEncoding(EncodingXML)(client)
+
+	gock.New(testURL).Get("/restconf/data/Cisco-IOS-XE-native:native/hostname").
+		MatchHeader("Accept", "application/yang-data\\+xml").
+		Reply(200).
+		SetHeader("Content-Type", "application/yang-data+xml").
+		BodyString(`<hostname xmlns="http://cisco.com/ns/yang/Cisco-IOS-XE-native">R1</hostname>`)
+	res, err := client.GetData("Cisco-IOS-XE-native:native/hostname")
+	assert.NoError(t, err)
+	assert.Equal(t, "R1", res.Res.Get("Cisco-IOS-XE-native:hostname").String())
+
+	gock.New(testURL).Put("/restconf/data/Cisco-IOS-XE-native:native/hostname").
+		MatchHeader("Content-Type", "application/yang-data\\+xml").
+		Reply(409).
+		SetHeader("Content-Type", "application/yang-data+xml").
+		BodyString(`<errors xmlns="urn:ietf:params:xml:ns:yang:ietf-restconf"><error><error-type>application</error-type><error-tag>data-exists</error-tag></error></errors>`)
+	res, err = client.PutData("Cisco-IOS-XE-native:native/hostname", `<hostname xmlns="http://cisco.com/ns/yang/Cisco-IOS-XE-native">R1</hostname>`)
+	assert.Error(t, err)
+	assert.Equal(t, "data-exists", res.Errors.Error[0].ErrorTag)
+
+	// JSON body
+	gock.New(testURL).Put("/restconf/data/Cisco-IOS-XE-native:native/hostname").
+		MatchHeader("Content-Type", "application/yang-data\\+json").
+		MatchHeader("Accept", "application/yang-data\\+xml").
+		Reply(204)
+	_, err = client.PutData("Cisco-IOS-XE-native:native/hostname", Body{}.Set("Cisco-IOS-XE-native:hostname", "R1").Str)
+	assert.NoError(t, err)
+	assert.True(t, gock.IsDone())
+}
+
+// TestDataEndpoint tests the WithDataEndpoint modifier.
+func TestDataEndpoint(t *testing.T) {
+	defer gock.Off()
+	client, _ := NewClient(testURL, "usr", "pwd", true, SkipDiscovery("/restconf", false), MaxRetries(0), WithDataEndpoint("/config"))
+	gock.InterceptClient(client.HttpClient)
+
+	gock.New(testURL).Get("/restconf/config/url").Reply(200)
+	_, err := client.GetData("url")
+	assert.NoError(t, err)
+	assert.True(t, gock.IsDone())
+}
+
+// TestAutoPrefix tests the WithAutoPrefix modifier.
+func TestAutoPrefix(t *testing.T) {
+	defer gock.Off()
+	client := testClient()
+	WithAutoPrefix()(client)
+
+	gock.New(testURL).Get("/restconf/data/ietf-yang-library:yang-library/module-set").
+		Reply(200).
+		BodyString(`{"ietf-yang-library:module-set":[{"name":"complete","module":[{"name":"Cisco-IOS-XE-native"},{"name":"ietf-interfaces"},{"name":"openconfig-interfaces"},{"name":"ietf-system"}]}]}`)
+	gock.New(testURL).Get("/restconf/data$").MatchParam("depth", "1").
+		Reply(200).
+		BodyString(`{"Cisco-IOS-XE-native:native":{},"a:routing":{}}`)
+	gock.New(testURL).Get("/restconf/data/Cisco-IOS-XE-native:native/hostname").Reply(200)
+	_, err := client.GetData("native/hostname")
+	assert.NoError(t, err)
+	assert.True(t, gock.IsDone())
+
+	assert.Equal(t, "/data/Cisco-IOS-XE-native:native", client.addPrefix("/data/native"))
+	assert.Equal(t, "/data/Cisco-IOS-XE-native:native?depth=1", client.addPrefix("/data/native?depth=1"))
+	assert.Equal(t, "/data/interfaces", client.addPrefix("/data/interfaces"))
+	assert.Equal(t, "/data/ietf-system:system", client.addPrefix("/data/system"))
+	assert.Equal(t, "/data/a:routing", client.addPrefix("/data/routing"))
+	assert.Equal(t, "/data/unknown", client.addPrefix("/data/unknown"))
+	assert.Equal(t, "/operations/native", client.addPrefix("/operations/native"))
+}
+
+// TestDynamicHeader tests the WithDynamicHeader modifier.
+func TestDynamicHeader(t *testing.T) {
+	counter := 0
+	client, _ := NewClient(testURL, "usr", "pwd", true, SkipDiscovery("/restconf", false), WithDynamicHeader("X-Nonce", func() string {
+		counter++
+		return strconv.Itoa(counter)
+	}))
+	req := client.NewReq("GET", "/data/url", nil)
+	assert.Equal(t, "1", req.HttpReq.Header.Get("X-Nonce"))
+	req = client.NewReq("GET", "/data/url", nil)
+	assert.Equal(t, "2", req.HttpReq.Header.Get("X-Nonce"))
+}
+
+// TestAuth tests the WithTokenAuth and WithTokenSource modifiers.
+func TestAuth(t *testing.T) {
+	client, _ := NewClient(testURL, "usr", "pwd", true, SkipDiscovery("/restconf", false))
+	req := client.NewReq("GET", "/data/url", nil)
+	usr, pwd, ok := req.HttpReq.BasicAuth()
+	assert.True(t, ok)
+	assert.Equal(t, "usr", usr)
+	assert.Equal(t, "pwd", pwd)
+
+	client, _ = NewClient(testURL, "", "", true, SkipDiscovery("/restconf", false), WithTokenAuth("abc"))
+	req = client.NewReq("GET", "/data/url", nil)
+	assert.Equal(t, "Bearer abc", req.HttpReq.Header.Get("Authorization"))
+
+	defer gock.Off()
+	tokens := 0
+	client, _ = NewClient(testURL, "", "", true, SkipDiscovery("/restconf", false), MaxRetries(0), WithTokenSource(func() (string, error) {
+		tokens++
+		return "token" + strconv.Itoa(tokens), nil
+	}))
+	gock.InterceptClient(client.HttpClient)
+	gock.New(testURL).Get("/restconf/data/url").MatchHeader("Authorization", "Bearer token1").Reply(200)
+	gock.New(testURL).Get("/restconf/data/url").MatchHeader("Authorization", "Bearer token2").Reply(200)
+	_, err := client.GetData("url")
+	assert.NoError(t, err)
+	_, err = client.GetData("url")
+	assert.NoError(t, err)
+	assert.True(t, gock.IsDone())
+}
+
+// TestIdempotencyKeys tests the WithIdempotencyKeys modifier.
+func TestIdempotencyKeys(t *testing.T) {
+	defer gock.Off()
+	client := testClient()
+	WithIdempotencyKeys("Idempotency-Key")(client)
+
+	gock.New(testURL).Post("/restconf/data/url").HeaderPresent("Idempotency-Key").Reply(200)
+	_, err := client.PostData("url", "{}")
+	assert.NoError(t, err)
+	assert.Regexp(t, "^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$", newUuid())
+}
+
+// TestClientOperations tests the Client::Operations method.
+func TestClientOperations(t *testing.T) {
+	defer gock.Off()
+	client := testClient()
+
+	gock.New(testURL).Get("/restconf/operations").Reply(200).BodyString(`{"ietf-restconf:operations":{"example-jukebox:play":[null],"ietf-netconf:validate":[null]}}`)
+	operations, err := client.Operations()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"example-jukebox:play", "ietf-netconf:validate"}, operations)
+}
+
+// TestClientOperation tests the Client::Operation method.
+func TestClientOperation(t *testing.T) {
+	defer gock.Off()
+	client := testClient()
+
+	gock.New(testURL).Post("/restconf/operations/example-ops:reboot").
+		Reply(200).
+		BodyString(`{"example-ops:output":{"reboot-time":"2022-03-01T00:00:00Z"}}`)
+	res, err := client.Operation("example-ops:reboot", `{"example-ops:input":{"delay":5}}`)
+	assert.NoError(t, err)
+	assert.Equal(t, "2022-03-01T00:00:00Z", res.Res.Get("example-ops:output.reboot-time").String())
+}
+
+// TestDiscoverRoot tests the discovery of the data and operations resources.
+func TestDiscoverRoot(t *testing.T) {
+	defer gock.Off()
+	client, _ := NewClient(testURL, "usr", "pwd", true, MaxRetries(0))
+	gock.InterceptClient(client.HttpClient)
+	gock.New(testURL).Get("/.well-known/host-meta").Reply(200).BodyString(`<XRD xmlns='http://docs.oasis-open.org/ns/xri/xrd-1.0'><Link rel='restconf' href='/restconf'/></XRD>`)
+	gock.New(testURL).Get("/restconf$").Reply(200).BodyString(`{"ietf-restconf:restconf":{"data":"/restconf/ds","operations":{},"yang-library-version":"2019-01-04"}}`)
+	gock.New(testURL).Get("/restconf/ds/ietf-restconf-monitoring:restconf-state/capabilities").Reply(200).BodyString(`{"ietf-restconf-monitoring:capabilities": {"capability": []}}`)
+	assert.NoError(t, client.Discovery())
+	assert.Equal(t, "/ds", client.DataEndpoint)
+	assert.Equal(t, "/operations", client.OperationsEndpoint)
+	assert.Equal(t, "2019-01-04", client.YangLibraryVersion())
+
+	gock.New(testURL).Post("/restconf/operations/example-ops:reboot").Reply(204)
+	_, err := client.Operation("example-ops:reboot", "")
+	assert.NoError(t, err)
+	assert.True(t, gock.IsDone())
+}
+
+// TestYangLibraryVersion tests the Client::YangLibraryVersion method.
+func TestYangLibraryVersion(t *testing.T) {
+	defer gock.Off()
+	client := testClient()
+	assert.Equal(t, "", client.YangLibraryVersion())
+
+	gock.New(testURL).Get("/restconf/yang-library-version").Reply(200).BodyString(`{"ietf-restconf:yang-library-version":"2016-06-21"}`)
+	assert.NoError(t, client.Discovery())
+	assert.Equal(t, "2016-06-21", client.YangLibraryVersion())
+}
+
+// TestClientModuleList tests the Client::ModuleList method.
+func TestClientModuleList(t *testing.T) {
+	defer gock.Off()
+	client := testClient()
+
+	// RFC 8525
+	gock.New(testURL).Get("/restconf/data/ietf-yang-library:yang-library/module-set").
+		Reply(200).
+		BodyString(`{"ietf-yang-library:module-set":[{"name":"complete","module":[{"name":"a","revision":"2020-01-01","namespace":"urn:a","feature":["f1","f2"]}]}]}`)
+	modules, err := client.ModuleList()
+	assert.NoError(t, err)
+	assert.Equal(t, []Module{{Name: "a", Revision: "2020-01-01", Namespace: "urn:a", Feature: []string{"f1", "f2"}}}, modules)
+
+	// RFC 7895
+	gock.New(testURL).Get("/restconf/data/ietf-yang-library:yang-library/module-set").Reply(404)
+	gock.New(testURL).Get("/restconf/data/ietf-yang-library:modules-state/module").
+		Reply(200).
+		BodyString(`{"ietf-yang-library:module":[{"name":"b","revision":"2016-01-01","namespace":"urn:b","conformance-type":"implement"}]}`)
+	modules, err = client.ModuleList()
+	assert.NoError(t, err)
+	assert.Equal(t, []Module{{Name: "b", Revision: "2016-01-01", Namespace: "urn:b"}}, modules)
+}
+
+// TestClientGetSchema tests the Client::GetSchema method.
+func TestClientGetSchema(t *testing.T) {
+	defer gock.Off()
+	client := testClient()
+
+	gock.New(testURL).Get("/restconf/data/ietf-yang-library:yang-library/module-set").
+		Reply(200).
+		BodyString(`{"ietf-yang-library:module-set":[{"name":"complete","module":[{"name":"a","revision":"2020-01-01","namespace":"urn:a","location":["` + testURL + `/restconf/tailf/modules/a/2020-01-01"]}]}]}`)
+	gock.New(testURL).Get("/restconf/tailf/modules/a/2020-01-01").
+		MatchHeader("Accept", "application/yang").
+		Reply(200).
+		SetHeader("Content-Type", "application/yang").
+		BodyString("module a {}")
+	schema, err := client.GetSchema("a", "2020-01-01")
+	assert.NoError(t, err)
+	assert.Equal(t, "module a {}", schema)
+
+	gock.New(testURL).Get("/restconf/data/ietf-yang-library:yang-library/module-set").
+		Reply(200).
+		BodyString(`{"ietf-yang-library:module-set":[]}`)
+	gock.New(testURL).Get("/restconf/data/ietf-yang-library:modules-state/module").Reply(404)
+	_, err = client.GetSchema("b", "")
+	assert.Error(t, err)
+}
+
+// TestStaticCapabilities tests the WithStaticCapabilities modifier.
+func TestStaticCapabilities(t *testing.T) {
+	defer gock.Off()
+	client, _ := NewClient(testURL, "usr", "pwd", true, MaxRetries(0), WithStaticCapabilities("/api", []string{"urn:ietf:params:restconf:capability:yang-patch:1.0"}))
+	gock.InterceptClient(client.HttpClient)
+	assert.Equal(t, true, client.YangPatchCapability)
+
+	gock.New(testURL).Get("/api/data/url").Reply(200)
+	_, err := client.GetData("url")
+	assert.NoError(t, err)
+	assert.True(t, gock.IsDone())
+}
+
+// TestClientYangPatchDataCreatedPaths tests the Client::YangPatchData method with created resources.
+func TestClientYangPatchDataCreatedPaths(t *testing.T) {
+	defer gock.Off()
+	client := testClient()
+
+	gock.New(testURL).Patch("/restconf/data/Cisco-IOS-XE-native:native").Reply(200)
+	res, err := client.YangPatchData("Cisco-IOS-XE-native:native", "1", "", []YangPatchEdit{
+		NewYangPatchEdit("create", "/interface/Loopback=1", Body{}.Set("Cisco-IOS-XE-native:Loopback.name", 1)),
+		NewYangPatchEdit("merge", "/hostname", Body{}.Set("Cisco-IOS-XE-native:hostname", "R1")),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Cisco-IOS-XE-native:native/interface/Loopback=1"}, res.CreatedPaths())
+}
+
+// TestClientYangPatchDataRetryFailed tests the Client::YangPatchDataRetryFailed method.
+func TestClientYangPatchDataRetryFailed(t *testing.T) {
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		if len(bodies) == 1 {
+			w.WriteHeader(409)
+			w.Write([]byte(`{"ietf-yang-patch:yang-patch-status":{"patch-id":"1","edit-status":{"edit":[{"edit-id":"0","ok":[null]},{"edit-id":"1","errors":{"error":[{"error-type":"application","error-tag":"lock-denied"}]}}]}}}`))
+			return
+		}
+		w.WriteHeader(204)
+	}))
+	defer server.Close()
+	client, _ := NewClient(server.URL, "usr", "pwd", true, SkipDiscovery("/restconf", true), MaxRetries(1), BackoffMinDelay(0))
+
+	_, err := client.YangPatchDataRetryFailed("Cisco-IOS-XE-native:native", "1", "", []YangPatchEdit{
+		NewYangPatchEdit("merge", "/hostname", Body{}.Set("Cisco-IOS-XE-native:hostname", "R1")),
+		NewYangPatchEdit("merge", "/banner", Body{}.Set("Cisco-IOS-XE-native:banner", "B")),
+	})
+	assert.NoError(t, err)
+	assert.Len(t, bodies, 2)
+	assert.Contains(t, bodies[1], `"edit-id":"1"`)
+	assert.NotContains(t, bodies[1], `"edit-id":"0"`)
+}
+
+// TestClientGetDataFiltered tests the Client::GetDataFiltered method.
+func TestClientGetDataFiltered(t *testing.T) {
+	defer gock.Off()
+	client := testClient()
+
+	// Filter capability not supported
+	_, err := client.GetDataFiltered("url", "/a[b='c']")
+	assert.Error(t, err)
+
+	client.Capabilities = append(client.Capabilities, "urn:ietf:params:restconf:capability:filter:1.0")
+	gock.New(testURL).Get("/restconf/data/url").MatchParam("filter", `/a\[b='c'\]`).Reply(200)
+	_, err = client.GetDataFiltered("url", "/a[b='c']")
+	assert.NoError(t, err)
+}
+
+// TestNewReqUrl tests the URL composition of the Client::NewReq method.
+func TestNewReqUrl(t *testing.T) {
+	client, _ := NewClient(testURL, "usr", "pwd", true, SkipDiscovery("/restconf", false))
+	for _, uri := range []string{"/data/a:b", "/data/a:b/c=x%2Fy,z", "/data/a:b?depth=1", "/data/a:b/c=%20"} {
+		req := client.NewReq("GET", uri, nil)
+		expected, _ := http.NewRequest("GET", testURL+"/restconf"+uri, nil)
+		assert.Equal(t, expected.URL.String(), req.HttpReq.URL.String())
+		assert.Equal(t, expected.Host, req.HttpReq.Host)
+	}
+}
+
+// BenchmarkNewReq benchmarks the Client::NewReq method.
+func BenchmarkNewReq(b *testing.B) {
+	client, _ := NewClient(testURL, "usr", "pwd", true, SkipDiscovery("/restconf", false))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		client.NewReq("GET", "/data/Cisco-IOS-XE-native:native/interface/GigabitEthernet="+strconv.Itoa(i), nil)
+	}
+}
+
+// TestNonRetryableTags tests the WithNonRetryableTags modifier.
+func TestNonRetryableTags(t *testing.T) {
+	defer gock.Off()
+	client := testClient()
+	client.MaxRetries = 1
+	WithNonRetryableTags("in-use")(client)
+
+	gock.New(testURL).Put("/restconf/data/url").Reply(409).BodyString(`{"errors":{"error":[{"error-type":"application","error-tag":"in-use"}]}}`)
+	start := time.Now()
+	res, err := client.PutData("url", "{}")
+	assert.Error(t, err)
+	assert.Equal(t, 409, res.StatusCode)
+	assert.Less(t, time.Since(start).Seconds(), float64(client.BackoffMinDelay))
+}
+
+// TestRetryMethods tests the WithRetryMethods modifier.
+func TestRetryMethods(t *testing.T) {
+	defer gock.Off()
+	client := testClient()
+	client.MaxRetries = 1
+	client.BackoffMinDelay = 0
+	client.BackoffMaxDelay = 0
+
+	// POST is not retried by default
+	gock.New(testURL).Post("/restconf/data/url").Reply(503).BodyString(`{"errors":{"error":[{"error-type":"application","error-tag":"operation-failed"}]}}`)
+	gock.New(testURL).Post("/restconf/data/url").Reply(204)
+	_, err := client.PostData("url", "{}")
+	assert.Error(t, err)
+	assert.False(t, gock.IsDone())
+	gock.Off()
+
+	// POST is retried if configured
+	client = testClient()
+	client.MaxRetries = 1
+	client.BackoffMinDelay = 0
+	client.BackoffMaxDelay = 0
+	WithRetryMethods("GET", "POST")(client)
+	gock.New(testURL).Post("/restconf/data/url").Reply(503).BodyString(`{"errors":{"error":[{"error-type":"application","error-tag":"operation-failed"}]}}`)
+	gock.New(testURL).Post("/restconf/data/url").Reply(204)
+	_, err = client.PostData("url", "{}")
+	assert.NoError(t, err)
+	assert.True(t, gock.IsDone())
+}
+
+// TestJSONLogging tests the WithJSONLogging modifier.
+func TestJSONLogging(t *testing.T) {
+	defer gock.Off()
+	client := testClient()
+	var buf bytes.Buffer
+	WithJSONLogging(&buf)(client)
+
+	gock.New(testURL).Get("/restconf/data/url").Reply(404).BodyString(`{"errors":{"error":[{"error-type":"application","error-tag":"invalid-value"}]}}`)
+	client.GetData("url")
+	event := gjson.Parse(buf.String())
+	assert.Equal(t, "GET", event.Get("method").String())
+	assert.Equal(t, testURL+"/restconf/data/url", event.Get("url").String())
+	assert.Equal(t, int64(404), event.Get("status").Int())
+	assert.Equal(t, int64(1), event.Get("attempts").Int())
+	assert.Equal(t, "invalid-value", event.Get("error-tag").String())
+}
+
+// TestLogger tests the WithLogger modifier.
+func TestLogger(t *testing.T) {
+	defer gock.Off()
+	client := testClient()
+	logger := &testLogger{}
+	WithLogger(logger)(client)
+
+	gock.New(testURL).Get("/restconf/data/url").Reply(404)
+	client.GetData("url", Tag("a"))
+	assert.Contains(t, logger.messages, "DEBUG Exit from Do method")
+	assert.Equal(t, []interface{}{"method", "GET", "url", testURL + "/restconf/data/url", "tag", "a", "status", 404, "attempts", 1}, logger.errorFields)
+	assert.Contains(t, logger.messages, "ERROR HTTP Request failed: StatusCode 404, RESTCONF errors {Error:[]} {PatchId: GlobalStatus:{Ok:false Errors:{Error:[]}} EditStatus:{Edit:[]} Errors:{Error:[]}}")
+	assert.Equal(t, " a=1 b=c", formatKeysAndValues([]interface{}{"a", 1, "b", "c"}))
+
+	// Streamed body of a request which is not retried
+	gock.New(testURL).Post("/restconf/data/url").Reply(204)
+	client.PostData("url", `{"a":"b"}`)
+	assert.Contains(t, logger.messages, `DEBUG HTTP Request: POST, `+testURL+`/restconf/data/url, {"a":"b"}`)
+}
+
+// TestRedact tests the redaction of logged bodies.
+func TestRedact(t *testing.T) {
+	client, _ := NewClient(testURL, "usr", "pwd", true, RedactPatterns([]string{`community \S+`}), WithRedactor(func(body []byte) []byte {
+		return bytes.ToUpper(body)
+	}))
+	assert.Len(t, client.RedactPatterns, 1)
+
+	_, err := NewClient(testURL, "usr", "pwd", true, RedactPatterns([]string{`community \S+`, `(`}))
+	assert.ErrorContains(t, err, "Invalid redact pattern")
+
+	body := []byte(`{"a":{"enable-password":"x","name":"n","list":[{"key":"k1"},{"key":"k2"}],"snmp":"community public RO"}}`)
+	assert.Equal(t, `{"A":{"ENABLE-PASSWORD":"******","NAME":"N","LIST":[{"KEY":"******"},{"KEY":"******"}],"SNMP":"****** RO"}}`, string(client.redact(body)))
+	assert.Equal(t, "AUTHORIZATION: BASIC ******", string(client.redact([]byte("Authorization: Basic dXNyOnB3ZA=="))))
+}
+
+// TestTag tests the Tag modifier.
+func TestTag(t *testing.T) {
+	defer gock.Off()
+	client := testClient()
+	var buf bytes.Buffer
+	WithJSONLogging(&buf)(client)
+
+	gock.New(testURL).Get("/restconf/data/url").Reply(404)
+	_, err := client.GetData("url", Tag("backup"))
+	assert.ErrorContains(t, err, "backup: ")
+	assert.Equal(t, "backup", gjson.Parse(buf.String()).Get("tag").String())
+}
+
+// TestMaxConcurrency tests the WithMaxConcurrency modifier.
+func TestMaxConcurrency(t *testing.T) {
+	defer gock.Off()
+	client := testClient()
+	WithMaxConcurrency(1)(client)
+
+	gock.New(testURL).Get("/restconf/data/url").Reply(200)
+	_, err := client.GetData("url")
+	assert.NoError(t, err)
+
+	// Wait for a free slot is canceled
+	client.concurrency <- struct{}{}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = client.GetData("url", Context(ctx))
+	assert.ErrorIs(t, err, context.Canceled)
+	_, _, _, err = client.DoRawBytes("GET", "/data/url", nil, Context(ctx))
+	assert.ErrorIs(t, err, context.Canceled)
+	err = client.GetDataArrayStream("url", "a", func(gjson.Result) bool { return true }, Context(ctx))
+	assert.ErrorIs(t, err, context.Canceled)
+	<-client.concurrency
+
+	// A limit of 0 or less disables the limit
+	for _, n := range []int{0, -1} {
+		client := testClient()
+		WithMaxConcurrency(n)(client)
+		assert.Nil(t, client.concurrency)
+		gock.New(testURL).Get("/restconf/data/url").Reply(200)
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		_, err = client.GetData("url", Context(ctx))
+		cancel()
+		assert.NoError(t, err)
+	}
+}
+
+// TestServerTime tests the Client::ServerTime method.
+func TestServerTime(t *testing.T) {
+	defer gock.Off()
+	client := testClient()
+
+	gock.New(testURL).Get("/restconf/data/Cisco-IOS-XE-device-hardware-oper:device-hardware-data").
+		Reply(200).
+		BodyString(`{"Cisco-IOS-XE-device-hardware-oper:current-time":"2023-01-02T03:04:05+00:00"}`)
+	serverTime, err := client.ServerTime()
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC).Unix(), serverTime.Unix())
+
+	// Date header fallback
+	gock.New(testURL).Get("/restconf/data/Cisco-IOS-XE-device-hardware-oper:device-hardware-data").
+		Reply(404).
+		SetHeader("Date", "Mon, 02 Jan 2023 03:04:06 GMT")
+	serverTime, err = client.ServerTime()
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2023, 1, 2, 3, 4, 6, 0, time.UTC).Unix(), serverTime.Unix())
+}
+
+// TestClientUpdate tests the Client::Update method.
+func TestClientUpdate(t *testing.T) {
+	defer gock.Off()
+	client := testClient()
+	client.MaxRetries = 1
+
+	gock.New(testURL).Get("/restconf/data/url").Reply(200).SetHeader("ETag", `"1"`).BodyString(`{"a:b":{"c":1}}`)
+	gock.New(testURL).Put("/restconf/data/url").MatchHeader("If-Match", `"1"`).Reply(412)
+	gock.New(testURL).Get("/restconf/data/url").Reply(200).SetHeader("ETag", `"2"`).BodyString(`{"a:b":{"c":2}}`)
+	gock.New(testURL).Put("/restconf/data/url").MatchHeader("If-Match", `"2"`).Reply(204)
+	res, err := client.Update("url", func(current Body) Body {
+		return current.Set("a:b.d", current.Res().Res.Get("a:b.c").Int()+1)
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 204, res.StatusCode)
+	assert.True(t, gock.IsDone())
+}
+
+// TestClientWait tests the Client::Wait method.
+func TestClientWait(t *testing.T) {
+	defer gock.Off()
+	client := testClient()
+
+	gock.New(testURL).Get("/restconf/data/ietf-netconf-monitoring:netconf-state/datastores/datastore").
+		Reply(200).
+		BodyString(`{"ietf-netconf-monitoring:datastore":[{"name":"running","locks":{"global-lock":{"locked-by-session":1}}}]}`)
+	gock.New(testURL).Get("/restconf/data/ietf-netconf-monitoring:netconf-state/datastores/datastore").
+		Reply(200).
+		BodyString(`{"ietf-netconf-monitoring:datastore":[{"name":"running"},{"name":"candidate","locks":{"global-lock":{"locked-by-session":1}}}]}`)
+	assert.NoError(t, client.Wait())
+	assert.True(t, gock.IsDone())
+
+	// Wait resource not supported
+	gock.New(testURL).Get("/restconf/data/ietf-netconf-monitoring:netconf-state/datastores/datastore").Reply(404)
+	assert.NoError(t, client.Wait())
+}
+
+// TestClientWaitTimeout tests the WithWaitTimeout and WithWaitPollInterval options.
+func TestClientWaitTimeout(t *testing.T) {
+	defer gock.Off()
+	client := testClient()
+	WithWaitTimeout(100 * time.Millisecond)(client)
+	WithWaitPollInterval(10 * time.Millisecond)(client)
+
+	gock.New(testURL).Get("/restconf/data/ietf-netconf-monitoring:netconf-state/datastores/datastore").
+		Persist().
+		Reply(200).
+		BodyString(`{"ietf-netconf-monitoring:datastore":[{"name":"running","locks":{"global-lock":{"locked-by-session":1}}}]}`)
+	start := time.Now()
+	assert.ErrorContains(t, client.Wait(), "Timeout waiting for datastore")
+	assert.Less(t, time.Since(start), time.Second)
+
+	WithWaitTimeout(time.Minute)(client)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, client.Wait(Context(ctx)), context.DeadlineExceeded)
+}
+
+// TestClientWaitDatastore tests the WithWaitDatastore and WithWaitChecker options.
+func TestClientWaitDatastore(t *testing.T) {
+	defer gock.Off()
+	client := testClient()
+	WithWaitDatastore("candidate")(client)
+	WithWaitPollInterval(10 * time.Millisecond)(client)
+
+	gock.New(testURL).Get("/restconf/data/ietf-netconf-monitoring:netconf-state/datastores/datastore").
+		Reply(200).
+		BodyString(`{"ietf-netconf-monitoring:datastore":[{"name":"candidate","locks":{"global-lock":{"locked-by-session":1}}}]}`)
+	gock.New(testURL).Get("/restconf/data/ietf-netconf-monitoring:netconf-state/datastores/datastore").
+		Reply(200).
+		BodyString(`{"ietf-netconf-monitoring:datastore":[{"name":"running","locks":{"global-lock":{"locked-by-session":1}}},{"name":"candidate"}]}`)
+	assert.NoError(t, client.Wait())
+	assert.True(t, gock.IsDone())
+
+	WithWaitResource("vendor-system:system/commit-state")(client)
+	WithWaitChecker(func(res Res) bool {
+		return res.Res.Get("vendor-system:commit-state").String() == "idle"
+	})(client)
+	gock.New(testURL).Get("/restconf/data/vendor-system:system/commit-state").Reply(200).BodyString(`{"vendor-system:commit-state":"busy"}`)
+	gock.New(testURL).Get("/restconf/data/vendor-system:system/commit-state").Reply(200).BodyString(`{"vendor-system:commit-state":"idle"}`)
+	assert.NoError(t, client.Wait())
+	assert.True(t, gock.IsDone())
+}
+
+// TestClientCandidate tests the Datastore request modifier and the Client::Commit and Client::DiscardChanges methods.
+func TestClientCandidate(t *testing.T) {
+	defer gock.Off()
+	client := testClient()
+	assert.NoError(t, client.Discovery())
+
+	req := client.NewReq("GET", "/data/a:b", nil, Datastore(DatastoreOperational), Query("depth", "1"))
+	assert.Equal(t, testURL+"/restconf/ds/ietf-datastores:operational/a:b?depth=1", req.HttpReq.URL.String())
+	req = client.NewReq("GET", "/operations", nil, Datastore(DatastoreCandidate))
+	assert.Equal(t, testURL+"/restconf/operations", req.HttpReq.URL.String())
+
+	gock.New(testURL).Put("/restconf/ds/ietf-datastores:candidate/Cisco-IOS-XE-native:native/hostname").Reply(204)
+	_, err := client.PutData("Cisco-IOS-XE-native:native/hostname", `{"Cisco-IOS-XE-native:hostname":"R1"}`, Datastore(DatastoreCandidate))
+	assert.NoError(t, err)
+
+	gock.New(testURL).Post("/restconf/operations/ietf-netconf:commit").Reply(204)
+	_, err = client.Commit()
+	assert.NoError(t, err)
+
+	gock.New(testURL).Post("/restconf/operations/ietf-netconf:discard-changes").Reply(204)
+	_, err = client.DiscardChanges()
+	assert.NoError(t, err)
+	assert.True(t, gock.IsDone())
+}
+
+// TestClientGetDataArrayStream tests the Client::GetDataArrayStream method.
+func TestClientGetDataArrayStream(t *testing.T) {
+	defer gock.Off()
+	client := testClient()
+
+	body := `{"Cisco-IOS-XE-bgp-oper:neighbors":{"other":{"a":[1,{"b":2}]},"neighbor":[{"neighbor-id":"1.1.1.1"},{"neighbor-id":"2.2.2.2"},{"neighbor-id":"3.3.3.3"}]}}`
+	gock.New(testURL).Get("/restconf/data/neighbors").Reply(200).BodyString(body)
+	var ids []string
+	err := client.GetDataArrayStream("neighbors", "Cisco-IOS-XE-bgp-oper:neighbors.neighbor", func(neighbor gjson.Result) bool {
+		ids = append(ids, neighbor.Get("neighbor-id").String())
+		return true
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"1.1.1.1", "2.2.2.2", "3.3.3.3"}, ids)
+
+	// Stop early
+	gock.New(testURL).Get("/restconf/data/neighbors").Reply(200).BodyString(body)
+	ids = nil
+	err = client.GetDataArrayStream("neighbors", "Cisco-IOS-XE-bgp-oper:neighbors.neighbor", func(neighbor gjson.Result) bool {
+		ids = append(ids, neighbor.Get("neighbor-id").String())
+		return false
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"1.1.1.1"}, ids)
+
+	// Array not found
+	gock.New(testURL).Get("/restconf/data/neighbors").Reply(200).BodyString(`{"Cisco-IOS-XE-bgp-oper:neighbors":{}}`)
+	err = client.GetDataArrayStream("neighbors", "Cisco-IOS-XE-bgp-oper:neighbors.neighbor", func(neighbor gjson.Result) bool {
+		t.Fail()
+		return true
+	})
+	assert.NoError(t, err)
+
+	// HTTP error
+	gock.New(testURL).Get("/restconf/data/neighbors").Reply(404)
+	err = client.GetDataArrayStream("neighbors", "Cisco-IOS-XE-bgp-oper:neighbors.neighbor", func(neighbor gjson.Result) bool {
+		return true
+	})
+	assert.Error(t, err)
+
+	// Transient errors are retried
+	client.MaxRetries = 2
+	client.BackoffMinDelay = 0
+	client.BackoffMaxDelay = 0
+	gock.New(testURL).Get("/restconf/data/neighbors").Reply(429).SetHeader("Retry-After", "0")
+	gock.New(testURL).Get("/restconf/data/neighbors").Reply(503).BodyString(`{"ietf-restconf:errors":{"error":[{"error-type":"application","error-tag":"in-use"}]}}`)
+	gock.New(testURL).Get("/restconf/data/neighbors").Reply(200).BodyString(body)
+	ids = nil
+	err = client.GetDataArrayStream("neighbors", "Cisco-IOS-XE-bgp-oper:neighbors.neighbor", func(neighbor gjson.Result) bool {
+		ids = append(ids, neighbor.Get("neighbor-id").String())
+		return true
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"1.1.1.1", "2.2.2.2", "3.3.3.3"}, ids)
+	assert.True(t, gock.IsDone())
+
+	// Transient errors are not retried with NoRetry
+	gock.New(testURL).Get("/restconf/data/neighbors").Reply(429)
+	err = client.GetDataArrayStream("neighbors", "Cisco-IOS-XE-bgp-oper:neighbors.neighbor", func(neighbor gjson.Result) bool {
+		return true
+	}, NoRetry())
+	assert.Error(t, err)
+	assert.True(t, gock.IsDone())
+}
+
+// TestClientListKeys tests the Client::ListKeys method.
+func TestClientListKeys(t *testing.T) {
+	defer gock.Off()
+	client := testClient()
+
+	gock.New(testURL).Get("/restconf/data/Cisco-IOS-XE-native:native/interface/GigabitEthernet").MatchParam("fields", "name").
+		Reply(200).
+		BodyString(`{"Cisco-IOS-XE-native:GigabitEthernet":[{"name":"0/0/0"},{"name":"0/0/1"}]}`)
+	keys, err := client.ListKeys("Cisco-IOS-XE-native:native/interface/GigabitEthernet", "name")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"0/0/0", "0/0/1"}, keys)
+
+	gock.New(testURL).Get("/restconf/data/Cisco-IOS-XE-native:native/interface/GigabitEthernet").Reply(404)
+	keys, err = client.ListKeys("Cisco-IOS-XE-native:native/interface/GigabitEthernet", "name")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{}, keys)
+}
+
+// TestClientNoContent tests the handling of empty 2xx response bodies.
+func TestClientNoContent(t *testing.T) {
+	defer gock.Off()
+	client := testClient()
+
+	gock.New(testURL).Delete("/restconf/data/url").Reply(204)
+	res, err := client.DeleteData("url")
+	assert.NoError(t, err)
+	assert.Equal(t, 204, res.StatusCode)
+	assert.False(t, res.HasBody())
+
+	gock.New(testURL).Get("/restconf/data/url").Reply(200).BodyString(`{"a":"b"}`)
+	res, err = client.GetData("url")
+	assert.NoError(t, err)
+	assert.True(t, res.HasBody())
+}
+
+// TestClientCreateAndGet tests the Client::CreateAndGet method.
+func TestClientCreateAndGet(t *testing.T) {
+	defer gock.Off()
+	client := testClient()
+
+	gock.New(testURL).Post("/restconf/data/example-jukebox:jukebox/library").
+		Reply(201).
+		SetHeader("Location", testURL+"/restconf/data/example-jukebox:jukebox/library/artist=Nirvana")
+	gock.New(testURL).Get("/restconf/data/example-jukebox:jukebox/library/artist=Nirvana").
+		Reply(200).
+		BodyString(`{"example-jukebox:artist":[{"name":"Nirvana","id":7}]}`)
+	res, err := client.CreateAndGet("example-jukebox:jukebox/library", Body{}.Set("example-jukebox:artist.name", "Nirvana"))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(7), res.Res.Get("example-jukebox:artist.0.id").Int())
+
+	// Write-only modifiers are not applied to the GET request
+	gock.New(testURL).Post("/restconf/data/example-jukebox:jukebox/library/artist=Nirvana/album").
+		MatchParam("insert", "after").
+		MatchParam("point", "/example-jukebox:jukebox/library/artist=Nirvana/album=Nevermind").
+		MatchHeader("If-Match", "etag").
+		Reply(201).
+		SetHeader("Location", testURL+"/restconf/data/example-jukebox:jukebox/library/artist=Nirvana/album=Bleach")
+	gock.New(testURL).Get("/restconf/data/example-jukebox:jukebox/library/artist=Nirvana/album=Bleach").
+		AddMatcher(func(req *http.Request, _ *gock.Request) (bool, error) {
+			return req.URL.RawQuery == "depth=1" && req.Header.Get("If-Match") == "", nil
+		}).
+		Reply(200).
+		BodyString(`{"example-jukebox:album":[{"name":"Bleach","year":1989}]}`)
+	res, err = client.CreateAndGet("example-jukebox:jukebox/library/artist=Nirvana/album", Body{}.Set("example-jukebox:album.name", "Bleach"),
+		InsertAfter("example-jukebox:jukebox/library/artist=Nirvana/album", "Nevermind"), IfMatch("etag"), Depth(1))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1989), res.Res.Get("example-jukebox:album.0.year").Int())
+	assert.True(t, gock.IsDone())
+
+	// Missing Location header
+	gock.New(testURL).Post("/restconf/data/example-jukebox:jukebox/library").Reply(201)
+	_, err = client.CreateAndGet("example-jukebox:jukebox/library", Body{}.Set("example-jukebox:artist.name", "Nirvana"))
+	assert.Error(t, err)
+}
+
+// TestRestconfError tests the errors returned by failed requests.
+func TestRestconfError(t *testing.T) {
+	defer gock.Off()
+	client := testClient()
+
+	gock.New(testURL).Post("/restconf/data/url").
+		Reply(409).
+		BodyString(`{"ietf-restconf:errors":{"error":[{"error-type":"application","error-tag":"data-exists"}]}}`)
+	_, err := client.PostData("url", "{}")
+	var rcErr *RestconfError
+	assert.True(t, errors.As(err, &rcErr))
+	assert.Equal(t, 409, rcErr.StatusCode)
+	assert.Equal(t, "data-exists", rcErr.Errors.Error[0].ErrorTag)
+	assert.Equal(t, "HTTP Request failed: StatusCode 409, RESTCONF errors {Error:[{ErrorType:application ErrorTag:data-exists ErrorAppTag: ErrorPath: ErrorMessage: ErrorInfo:}]} {PatchId: GlobalStatus:{Ok:false Errors:{Error:[]}} EditStatus:{Edit:[]} Errors:{Error:[]}}", err.Error())
+
+	// Tagged request
+	gock.New(testURL).Post("/restconf/data/url").Reply(409)
+	_, err = client.PostData("url", "{}", Tag("create"))
+	assert.True(t, errors.As(err, &rcErr))
+}
+
+// TestClientSubscribe tests the Client::Subscribe method.
+func TestClientSubscribe(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/restconf/data/ietf-restconf-monitoring:restconf-state/streams/stream=NETCONF/access":
+			w.Write([]byte(`{"ietf-restconf-monitoring:access":[{"encoding":"xml","location":"` + server.URL + `/streams/NETCONF/xml"},{"encoding":"json","location":"` + server.URL + `/streams/NETCONF/json"}]}`))
+		case "/streams/NETCONF/json":
+			w.Header().Set("Content-Type", "text/event-stream")
+			w.Write([]byte("data: {\"ietf-restconf:notification\":{\"eventTime\":\"2013-12-21T00:01:00Z\",\n"))
+			w.Write([]byte("data: \"example-mod:event\":{\"event-class\":\"fault\"}}}\n\n"))
+			w.Write([]byte(": keep-alive\n\n"))
+			w.Write([]byte("data: {\"ietf-restconf:notification\":{\"eventTime\":\"2013-12-21T00:02:00Z\"}}\n\n"))
+			w.Write([]byte("data: {\"ietf-restconf:notification\":{\"eventTime\":\"2013-12-21T00:03:00Z\",\"example-mod:event\":{\"event-class\":\"state\"}}}\n\n"))
+		default:
+			w.WriteHeader(404)
+		}
+	}))
+	defer server.Close()
+	client, _ := NewClient(server.URL, "usr", "pwd", true, SkipDiscovery("/restconf", false), MaxRetries(0))
+
+	stream, err := client.Subscribe("NETCONF")
+	assert.NoError(t, err)
+	var events []string
+	for notification := range stream.Notifications {
+		assert.Equal(t, "example-mod:event", notification.Type)
+		events = append(events, notification.EventTime.Format(time.RFC3339)+" "+notification.Data.Get("event-class").String())
+	}
+	assert.Equal(t, []string{"2013-12-21T00:01:00Z fault", "2013-12-21T00:03:00Z state"}, events)
+	assert.Error(t, stream.Err())
+	stream.Close()
+
+	// Unknown stream
+	_, err = client.Subscribe("UNKNOWN")
+	assert.Error(t, err)
+}
+
+// TestClientSubscribeHeartbeatTimeout tests the reconnection of a silent stream after the heartbeat timeout.
+func TestClientSubscribeHeartbeatTimeout(t *testing.T) {
+	var server *httptest.Server
+	var connections int32
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/restconf/data/ietf-restconf-monitoring:restconf-state/streams/stream=NETCONF/access":
+			w.Write([]byte(`{"ietf-restconf-monitoring:access":[{"encoding":"json","location":"` + server.URL + `/streams/NETCONF/json"}]}`))
+		case "/streams/NETCONF/json":
+			if atomic.AddInt32(&connections, 1) > 1 {
+				w.WriteHeader(404)
+				return
+			}
+			w.Header().Set("Content-Type", "text/event-stream")
+			w.Write([]byte(": keep-alive\n\n"))
+			w.Write([]byte("data: {\"ietf-restconf:notification\":{\"eventTime\":\"2013-12-21T00:01:00Z\",\"example-mod:event\":{}}}\n\n"))
+			w.(http.Flusher).Flush()
+			// the stream stops without closing the connection
+			<-r.Context().Done()
+		default:
+			w.WriteHeader(404)
+		}
+	}))
+	defer server.Close()
+	client, _ := NewClient(server.URL, "usr", "pwd", true, SkipDiscovery("/restconf", false), MaxRetries(1), BackoffMinDelay(0), BackoffMaxDelay(0),
+		WithHeartbeatTimeout(100*time.Millisecond))
+
+	stream, err := client.Subscribe("NETCONF")
+	assert.NoError(t, err)
+	count := 0
+	for range stream.Notifications {
+		count++
+	}
+	assert.Equal(t, 1, count)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&connections))
+}
+
+// TestClientSubscribeReplay tests resuming a replay when reconnecting a notification stream.
+func TestClientSubscribeReplay(t *testing.T) {
+	var server *httptest.Server
+	var startTimes, headers []string
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/restconf/data/ietf-restconf-monitoring:restconf-state/streams/stream=NETCONF/access":
+			w.Write([]byte(`{"ietf-restconf-monitoring:access":[{"encoding":"json","location":"` + server.URL + `/streams/NETCONF/json"}]}`))
+		case "/streams/NETCONF/json":
+			startTimes = append(startTimes, r.URL.Query().Get("start-time"))
+			headers = append(headers, r.Header.Get("X-Request"))
+			if len(startTimes) > 2 {
+				w.WriteHeader(404)
+				return
+			}
+			w.Header().Set("Content-Type", "text/event-stream")
+			// the device replays all notifications since the start-time, including the last one
+			if len(startTimes) == 1 {
+				w.Write([]byte("data: {\"ietf-restconf:notification\":{\"eventTime\":\"2013-12-21T00:01:00Z\",\"example-mod:event\":{\"id\":1}}}\n\n"))
+			}
+			w.Write([]byte("data: {\"ietf-restconf:notification\":{\"eventTime\":\"2013-12-21T00:02:00Z\",\"example-mod:event\":{\"id\":2}}}\n\n"))
+			if len(startTimes) == 2 {
+				w.Write([]byte("data: {\"ietf-restconf:notification\":{\"eventTime\":\"2013-12-21T00:02:00Z\",\"example-mod:event\":{\"id\":3}}}\n\n"))
+			}
+		default:
+			w.WriteHeader(404)
+		}
+	}))
+	defer server.Close()
+	client, _ := NewClient(server.URL, "usr", "pwd", true, SkipDiscovery("/restconf", false), MaxRetries(1), BackoffMinDelay(0), BackoffMaxDelay(0),
+		WithDynamicHeader("X-Request", func() string { return "1" }))
+
+	stream, err := client.Subscribe("NETCONF", StartTime(time.Date(2013, 12, 21, 0, 0, 0, 0, time.UTC)))
+	assert.NoError(t, err)
+	var ids []int64
+	for notification := range stream.Notifications {
+		ids = append(ids, notification.Data.Get("id").Int())
+	}
+	assert.Equal(t, []int64{1, 2, 3}, ids)
+	assert.Equal(t, []string{"2013-12-21T00:00:00Z", "2013-12-21T00:02:00Z", "2013-12-21T00:02:00Z"}, startTimes)
+	assert.Equal(t, []string{"1", "1", "1"}, headers)
+}
+
+// TestClientOptionsData tests the Client::OptionsData method.
+func TestClientOptionsData(t *testing.T) {
+	var method string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method = r.Method
+		w.Header().Set("Allow", "GET, PUT, DELETE,OPTIONS")
+		w.WriteHeader(200)
+	}))
+	defer server.Close()
+	client, _ := NewClient(server.URL, "usr", "pwd", true, SkipDiscovery("/restconf", false), MaxRetries(0))
+
+	res, err := client.OptionsData("Cisco-IOS-XE-native:native/hostname")
+	assert.NoError(t, err)
+	assert.Equal(t, "OPTIONS", method)
+	assert.Equal(t, []string{"GET", "PUT", "DELETE", "OPTIONS"}, res.AllowedMethods)
+	assert.False(t, res.HasBody())
+}
+
+// TestClientValidate tests the Client::Validate method.
+func TestClientValidate(t *testing.T) {
+	defer gock.Off()
+	client := testClient()
+
+	var body string
+	gock.New(testURL).Post("/restconf/operations/ietf-netconf:validate").
+		AddMatcher(func(req *http.Request, _ *gock.Request) (bool, error) {
+			b, err := ioutil.ReadAll(req.Body)
+			body = string(b)
+			return true, err
+		}).
+		Reply(204)
+	_, err := client.Validate("Cisco-IOS-XE-native:native/hostname", Body{}.Set("Cisco-IOS-XE-native:hostname", "R1"))
+	assert.NoError(t, err)
+	assert.True(t, gock.IsDone())
+	assert.Equal(t, `{"ietf-netconf:input":{"source":{"config":{"Cisco-IOS-XE-native:native":{"Cisco-IOS-XE-native:hostname":"R1"}}}}}`, body)
+
+	_, err = client.Validate("Cisco-IOS-XE-native:native/interface/GigabitEthernet=1/description", Body{}.Set("description", "a"))
+	assert.Error(t, err)
+
+	WithValidateQuery("test-only", "urn:vendor:restconf:capability:test-only:1.0")(client)
+	_, err = client.Validate("url", Body{}.Set("a", "b"))
+	assert.ErrorContains(t, err, "not supported by device")
+
+	client.setCapabilities(append(client.Capabilities, "urn:vendor:restconf:capability:test-only:1.0"))
+	gock.New(testURL).Patch("/restconf/data/url").MatchParam("test-only", "true").
+		Reply(400).
+		BodyString(`{"ietf-restconf:errors":{"error":[{"error-type":"application","error-tag":"invalid-value"}]}}`)
+	mods := make([]func(*Req), 1, 2)
+	mods[0] = Query("a", "b")
+	_, err = client.Validate("url", Body{}.Set("a", "b"), mods...)
+	assert.Error(t, err)
+	assert.True(t, gock.IsDone())
+	assert.Nil(t, mods[:2][1])
+}
+
+// TestClientDeprecation tests the capturing of Deprecation and Sunset headers.
+func TestClientDeprecation(t *testing.T) {
+	defer gock.Off()
+	client := testClient()
+	WithDeprecationWarnings()(client)
+
+	gock.New(testURL).Get("/restconf/data/url").
+		Reply(200).
+		SetHeader("Deprecation", "@1688169599").
+		SetHeader("Sunset", "Sun, 30 Jun 2024 23:59:59 GMT")
+	res, err := client.GetData("url")
+	assert.NoError(t, err)
+	assert.Equal(t, "@1688169599", res.Deprecation)
+	assert.Equal(t, "Sun, 30 Jun 2024 23:59:59 GMT", res.Sunset)
+
+	gock.New(testURL).Get("/restconf/data/url").Reply(200)
+	res, _ = client.GetData("url")
+	assert.Equal(t, "", res.Deprecation)
+	assert.Equal(t, "", res.Sunset)
+}
+
+// TestClientWaitPredicate tests the Client::Wait method with a custom predicate.
+func TestClientWaitPredicate(t *testing.T) {
+	defer gock.Off()
+	client := testClient()
+	WithWaitPredicate(func(datastore DatastoreModel) bool {
+		return datastore.Locks.GlobalLock != nil
+	})(client)
+	WithWaitDatastore("running")(client)
+
+	gock.New(testURL).Get("/restconf/data/ietf-netconf-monitoring:netconf-state/datastores/datastore").
+		Reply(200).
+		BodyString(`{"ietf-netconf-monitoring:datastore":[{"name":"running"},{"name":"candidate","locks":{"global-lock":{"locked-by-session":1}}}]}`)
+	gock.New(testURL).Get("/restconf/data/ietf-netconf-monitoring:netconf-state/datastores/datastore").
+		Reply(200).
+		BodyString(`{"ietf-netconf-monitoring:datastore":[{"name":"running"},{"name":"candidate"}]}`)
+	assert.NoError(t, client.Wait())
+	assert.True(t, gock.IsDone())
+}
+
+// TestClientPutDataFromFile tests the Client::PutDataFromFile method.
+func TestClientPutDataFromFile(t *testing.T) {
+	var body []byte
+	var contentLength int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		contentLength = r.ContentLength
+		body, _ = ioutil.ReadAll(r.Body)
+		w.WriteHeader(204)
+	}))
+	defer server.Close()
+	client, _ := NewClient(server.URL, "usr", "pwd", true, SkipDiscovery("/restconf", false), MaxRetries(0))
+
+	filePath := filepath.Join(t.TempDir(), "config.json")
+	ioutil.WriteFile(filePath, []byte(`{"Cisco-IOS-XE-native:native":{"hostname":"R1"}}`), 0644)
+	_, err := client.PutDataFromFile("Cisco-IOS-XE-native:native", filePath)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"Cisco-IOS-XE-native:native":{"hostname":"R1"}}`, string(body))
+	assert.Equal(t, int64(len(body)), contentLength)
+
+	// File not found
+	_, err = client.PutDataFromFile("Cisco-IOS-XE-native:native", filepath.Join(t.TempDir(), "missing.json"))
+	assert.Error(t, err)
+}
+
+// TestClientCountList tests the Client::CountList method.
+func TestClientCountList(t *testing.T) {
+	defer gock.Off()
+	client := testClient()
+
+	gock.New(testURL).Get("/restconf/data/url").MatchParam("depth", "1").Reply(200).BodyString(`{"a:b":[{"name":"1"},{"name":"2"}]}`)
+	count, err := client.CountList("url")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	// Empty list
+	gock.New(testURL).Get("/restconf/data/url").Reply(404)
+	count, err = client.CountList("url")
+	assert.NoError(t, err)
+	assert.Equal(t, 0, count)
+}
+
+// TestOperationDeadline tests the WithOperationDeadline modifier.
+func TestOperationDeadline(t *testing.T) {
+	defer gock.Off()
+	client := testClient()
+	client.MaxRetries = 10
+	client.BackoffMinDelay = 10
+	WithOperationDeadline(100 * time.Millisecond)(client)
+
+	gock.New(testURL).Get("/restconf/data/url").Persist().Reply(503).BodyString(`{"errors":{"error":[{"error-type":"application","error-tag":"operation-failed"}]}}`)
+	start := time.Now()
+	_, err := client.GetData("url")
+	assert.Error(t, err)
+	assert.Less(t, time.Since(start).Seconds(), float64(client.BackoffMinDelay))
+}
+
+// TestDiscoverAcceptPatch tests the WithAcceptPatchDiscovery modifier.
+func TestDiscoverAcceptPatch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/.well-known/host-meta":
+			w.Write([]byte(`<XRD xmlns='http://docs.oasis-open.org/ns/xri/xrd-1.0'><Link rel='restconf' href='/restconf'/></XRD>`))
+		case r.Method == "OPTIONS" && r.URL.Path == "/restconf/data":
+			w.Header().Set("Accept-Patch", "application/yang-data+json, application/yang-patch+json")
+		default:
+			w.Write([]byte(`{"ietf-restconf-monitoring:capabilities":{"capability":[]}}`))
+		}
+	}))
+	defer server.Close()
+	client, _ := NewClient(server.URL, "usr", "pwd", true, WithAcceptPatchDiscovery())
+
+	assert.NoError(t, client.Discovery())
+	assert.True(t, client.YangPatchCapability)
+}
+
+// TestClientYangPatchDataStatus tests the Client::YangPatchDataStatus method.
+func TestClientYangPatchDataStatus(t *testing.T) {
+	defer gock.Off()
+	client := testClient()
+	edits := []YangPatchEdit{NewYangPatchEdit("merge", "/hostname", Body{}.Set("Cisco-IOS-XE-native:hostname", "R1"))}
+
+	// Success
+	gock.New(testURL).Patch("/restconf/data/Cisco-IOS-XE-native:native").Reply(204)
+	status, err := client.YangPatchDataStatus("Cisco-IOS-XE-native:native", "1", "", edits)
+	assert.NoError(t, err)
+	assert.True(t, bool(status.GlobalStatus.Ok))
+
+	// Failed edits
+	gock.New(testURL).Patch("/restconf/data/Cisco-IOS-XE-native:native").
+		Reply(400).
+		BodyString(`{"ietf-yang-patch:yang-patch-status":{"patch-id":"1","edit-status":{"edit":[{"edit-id":"0","errors":{"error":[{"error-type":"application","error-tag":"invalid-value"}]}}]}}}`)
+	status, err = client.YangPatchDataStatus("Cisco-IOS-XE-native:native", "1", "", edits)
+	assert.NoError(t, err)
+	assert.Equal(t, "invalid-value", status.EditStatus.Edit[0].Errors.Error[0].ErrorTag)
+
+	// Connection error
+	gock.New(testURL).Patch("/restconf/data/Cisco-IOS-XE-native:native").ReplyError(errors.New("fail"))
+	_, err = client.YangPatchDataStatus("Cisco-IOS-XE-native:native", "1", "", edits)
+	assert.Error(t, err)
+}
+
+// TestCheckTransientError tests the Client::checkTransientError method.
+func TestCheckTransientError(t *testing.T) {
+	client, _ := NewClient(testURL, "usr", "pwd", true)
+	res := Res{StatusCode: 409, Errors: ErrorsModel{Error: []ErrorModel{{ErrorTag: "invalid-value"}, {ErrorTag: "lock-denied"}}}}
+	transientError, ok := client.checkTransientError(res)
+	assert.True(t, ok)
+	assert.Equal(t, "error-tag=lock-denied", transientError.String())
+
+	res = Res{StatusCode: 400, Errors: ErrorsModel{Error: []ErrorModel{{ErrorTag: "invalid-value"}}}}
+	_, ok = client.checkTransientError(res)
+	assert.False(t, ok)
+
+	transientError, ok = client.checkTransientError(Res{StatusCode: 429})
+	assert.True(t, ok)
+	assert.Equal(t, "status-code=429", transientError.String())
+	transientError, ok = client.checkTransientError(Res{StatusCode: 503, Header: http.Header{"Retry-After": {"1"}}})
+	assert.True(t, ok)
+	assert.Equal(t, "status-code=503", transientError.String())
+	_, ok = client.checkTransientError(Res{StatusCode: 503})
+	assert.False(t, ok)
+	_, ok = client.checkTransientError(Res{StatusCode: 501})
+	assert.False(t, ok)
+	_, ok = client.checkTransientError(Res{StatusCode: 400})
+	assert.False(t, ok)
+
+	assert.Equal(t, "status-code=400, error-tag=invalid-value, error-message=inconsistent value: Device refused one or more commands", TransientErrors[0].String())
+}
+
+// TestWithTransientErrors tests the WithTransientErrors option.
+func TestWithTransientErrors(t *testing.T) {
+	res := Res{StatusCode: 400, Errors: ErrorsModel{Error: []ErrorModel{{ErrorTag: "operation-failed", ErrorAppTag: "busy"}}}}
+	client, _ := NewClient(testURL, "usr", "pwd", true, WithTransientErrors([]TransientError{{ErrorAppTag: "^busy$"}}, false))
+	_, ok := client.checkTransientError(res)
+	assert.True(t, ok)
+	assert.True(t, client.checkTransientStatusCode(503))
+
+	client, _ = NewClient(testURL, "usr", "pwd", true, WithTransientErrors([]TransientError{{ErrorAppTag: "^busy$"}}, true))
+	_, ok = client.checkTransientError(res)
+	assert.True(t, ok)
+	assert.False(t, client.checkTransientStatusCode(503))
+}
+
+// TestNoRetry tests the NoRetry request modifier and the WithNonRetryableMethods option.
+func TestNoRetry(t *testing.T) {
+	defer gock.Off()
+	client := testClient()
+	client.MaxRetries = 2
+
+	gock.New(testURL).Get("/restconf/data/url").Reply(503).BodyString(`{"errors":{"error":[{"error-tag":"in-use"}]}}`)
+	_, err := client.GetData("url", NoRetry())
+	assert.Error(t, err)
+	assert.True(t, gock.IsDone())
+
+	WithNonRetryableMethods("delete")(client)
+	assert.Equal(t, []string{"GET", "HEAD", "OPTIONS", "PUT", "PATCH"}, client.RetryMethods)
+	assert.Equal(t, []string{"GET", "HEAD", "OPTIONS", "PUT", "DELETE", "PATCH"}, DefaultRetryMethods)
+
+	gock.New(testURL).Delete("/restconf/data/url").Reply(503).BodyString(`{"errors":{"error":[{"error-tag":"in-use"}]}}`)
+	_, err = client.DeleteData("url")
+	assert.Error(t, err)
+	assert.True(t, gock.IsDone())
+}
+
+// TestNoRetryRaw tests the NoRetry request modifier with DoRawBytes.
+func TestNoRetryRaw(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.WriteHeader(503)
+	}))
+	defer server.Close()
+	client, _ := NewClient(server.URL, "usr", "pwd", true, SkipDiscovery("/restconf", false), MaxRetries(2), BackoffMinDelay(0), BackoffMaxDelay(0))
+
+	status, _, _, err := client.DoRawBytes("GET", "/data/url", nil, NoRetry())
+	assert.NoError(t, err)
+	assert.Equal(t, 503, status)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&requests))
+
+	atomic.StoreInt32(&requests, 0)
+	client.DoRawBytes("GET", "/data/url", nil)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&requests))
+}
+
+// TestBackoffJitter tests the WithBackoffJitter option.
+func TestBackoffJitter(t *testing.T) {
+	client, _ := NewClient(testURL, "usr", "pwd", true, BackoffMinDelay(1), BackoffMaxDelay(60), BackoffDelayFactor(2), WithBackoffJitter(BackoffJitterNone))
+	assert.Equal(t, 1*time.Second, client.backoffDelay(0))
+	assert.Equal(t, 4*time.Second, client.backoffDelay(2))
+	assert.Equal(t, 60*time.Second, client.backoffDelay(10))
+
+	for _, jitter := range []BackoffJitter{BackoffJitterEqual, BackoffJitterFull} {
+		WithBackoffJitter(jitter)(client)
+		for i := 0; i < 100; i++ {
+			delay := client.backoffDelay(3)
+			assert.LessOrEqual(t, delay, 8*time.Second)
+			if jitter == BackoffJitterEqual {
+				assert.GreaterOrEqual(t, delay, 4500*time.Millisecond)
+			} else {
+				assert.GreaterOrEqual(t, delay, 1*time.Second)
+			}
+		}
+	}
+}
+
+// TestRandSource tests the WithRandSource option.
+func TestRandSource(t *testing.T) {
+	client1, _ := NewClient(testURL, "usr", "pwd", true, WithRandSource(mrand.NewSource(1)))
+	client2, _ := NewClient(testURL, "usr", "pwd", true, WithRandSource(mrand.NewSource(1)))
+	for i := 0; i < 5; i++ {
+		assert.Equal(t, client1.backoffDelay(i), client2.backoffDelay(i))
+	}
+
+	// The clone shares the source
+	clone, _ := client1.Clone()
+	for i := 0; i < 5; i++ {
+		assert.Equal(t, client2.backoffDelay(i), client1.backoffDelay(i))
+		assert.Equal(t, client2.backoffDelay(i), clone.backoffDelay(i))
+	}
+}
+
+// TestResAttempts tests the Res.Attempts field.
+func TestResAttempts(t *testing.T) {
+	defer gock.Off()
+	client := testClient()
+	client.MaxRetries = 1
+	client.BackoffMinDelay = 0
+	client.BackoffMaxDelay = 0
+
+	gock.New(testURL).Get("/restconf/data/url").Reply(200)
+	res, _ := client.GetData("url")
+	assert.Equal(t, 1, res.Attempts)
+
+	gock.New(testURL).Get("/restconf/data/url").Reply(503).BodyString(`{"errors":{"error":[{"error-tag":"in-use"}]}}`)
+	gock.New(testURL).Get("/restconf/data/url").Reply(200)
+	res, err := client.GetData("url")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, res.Attempts)
+
+	gock.New(testURL).Get("/restconf/data/url").Times(2).Reply(503).BodyString(`{"errors":{"error":[{"error-tag":"in-use"}]}}`)
+	res, err = client.GetData("url")
+	assert.Error(t, err)
+	assert.Equal(t, 2, res.Attempts)
+}
+
+type testObserver struct {
+	requests []string
+}
+
+func (o *testObserver) ObserveRequest(method, path, tag string, statusCode int, attempts int, duration time.Duration, err error) {
+	o.requests = append(o.requests, method+" "+path+" "+tag+" "+strconv.Itoa(statusCode)+" "+strconv.Itoa(attempts)+" "+strconv.FormatBool(err != nil))
+}
+
+// TestObserver tests the WithObserver option.
+func TestObserver(t *testing.T) {
+	defer gock.Off()
+	client := testClient()
+	client.Discovery()
+	observer := &testObserver{}
+	WithObserver(observer)(client)
+
+	gock.New(testURL).Get("/restconf/data/url").Reply(200)
+	client.GetData("url")
+	gock.New(testURL).Delete("/restconf/data/url").Reply(404)
+	client.DeleteData("url", Tag("cleanup"))
+	gock.New(testURL).Get("/restconf/data/url").ReplyError(errors.New("fail"))
+	client.GetData("url")
+	gock.New(testURL).Get("/restconf/data/raw").Reply(200)
+	client.DoRawBytes("GET", "/data/raw", nil)
+	gock.New(testURL).Get("/restconf/data/stream").Reply(200).BodyString(`{"a":[1]}`)
+	client.GetDataArrayStream("stream", "a", func(gjson.Result) bool { return true })
+
+	assert.Equal(t, []string{
+		"GET /restconf/data/url  200 1 false",
+		"DELETE /restconf/data/url cleanup 404 1 true",
+		"GET /restconf/data/url  0 1 true",
+		"GET /restconf/data/raw  200 1 false",
+		"GET /restconf/data/stream  200 1 false",
+	}, observer.requests)
+}
+
+// TestConcurrentRequests tests that GET requests are not blocked by write requests,
+// while write requests are serialized. Run with -race.
+func TestConcurrentRequests(t *testing.T) {
+	var inFlight, maxInFlight int32
+	getServed := make(chan struct{}, 10)
+	putStarted := make(chan struct{}, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" {
+			getServed <- struct{}{}
+			w.WriteHeader(200)
+			return
+		}
+		n := atomic.AddInt32(&inFlight, 1)
+		for {
+			max := atomic.LoadInt32(&maxInFlight)
+			if n <= max || atomic.CompareAndSwapInt32(&maxInFlight, max, n) {
+				break
+			}
+		}
+		putStarted <- struct{}{}
+		time.Sleep(10 * time.Millisecond)
+		atomic.AddInt32(&inFlight, -1)
+		w.WriteHeader(204)
+	}))
+	defer server.Close()
+	client, _ := NewClient(server.URL, "usr", "pwd", true, SkipDiscovery("/restconf", false), MaxRetries(0))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			client.PutData("url", "{}")
+		}()
+	}
+	<-putStarted
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := client.GetData("url")
+			assert.NoError(t, err)
+		}()
+	}
+	// GET requests are served while write requests are still pending
+	for i := 0; i < 5; i++ {
+		select {
+		case <-getServed:
+		case <-time.After(40 * time.Millisecond):
+			t.Fatal("GET request blocked by write requests")
+		}
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), atomic.LoadInt32(&maxInFlight))
+}
+
+// TestClientGetDataBatch tests the Client::GetDataBatch method.
+func TestClientGetDataBatch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimPrefix(r.URL.Path, "/restconf/data/a:")
+		if name == "missing" {
+			w.WriteHeader(404)
+			return
+		}
+		w.Header().Set("Content-Type", "application/yang-data+json")
+		w.Write([]byte(`{"a:` + name + `":"` + name + `"}`))
+	}))
+	defer server.Close()
+	client, _ := NewClient(server.URL, "usr", "pwd", true, SkipDiscovery("/restconf", false), MaxRetries(0))
+
+	var paths []string
+	for i := 0; i < 10; i++ {
+		paths = append(paths, "a:leaf"+strconv.Itoa(i))
+	}
+	res, err := client.GetDataBatch(paths, 3)
+	assert.NoError(t, err)
+	assert.Len(t, res, 10)
+	for i, r := range res {
+		assert.Equal(t, "leaf"+strconv.Itoa(i), r.Res.Get("a:leaf"+strconv.Itoa(i)).String())
+	}
+
+	res, err = client.GetDataBatch([]string{"a:leaf1", "a:missing"}, 2)
+	var batchErr *BatchError
+	assert.True(t, errors.As(err, &batchErr))
+	assert.Len(t, batchErr.Errors, 1)
+	assert.ErrorContains(t, err, "a:missing: HTTP Request failed: StatusCode 404")
+	assert.Equal(t, "leaf1", res[0].Res.Get("a:leaf1").String())
+}
+
+// TestRateLimit tests the WithRateLimit option.
+func TestRateLimit(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(200)
+	}))
+	defer server.Close()
+	client, _ := NewClient(server.URL, "usr", "pwd", true, SkipDiscovery("/restconf", false), MaxRetries(0), WithRateLimit(20, 2))
+
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		_, err := client.GetData("url")
+		assert.NoError(t, err)
+	}
+	// 2 requests of the burst, 2 requests at 50ms intervals
+	assert.GreaterOrEqual(t, time.Since(start), 90*time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	client.rateLimiter.reserve()
+	client.rateLimiter.reserve()
+	_, err := client.GetData("url", Context(ctx))
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+// TestRetryAfter tests that the Retry-After header is honored.
+func TestRetryAfter(t *testing.T) {
+	assert.Equal(t, 10*time.Second, parseRetryAfter("10"))
+	assert.Equal(t, time.Duration(0), parseRetryAfter("-1"))
+	assert.Equal(t, time.Duration(0), parseRetryAfter("soon"))
+	assert.Equal(t, time.Duration(0), parseRetryAfter(time.Now().Add(-time.Minute).UTC().Format(http.TimeFormat)))
+	d := parseRetryAfter(time.Now().Add(time.Minute).UTC().Format(http.TimeFormat))
+	assert.True(t, d > 50*time.Second && d <= time.Minute)
+
+	defer gock.Off()
+	client := testClient()
+	client.MaxRetries = 1
+	client.BackoffMinDelay = 0
+	client.BackoffMaxDelay = 1
+
+	gock.New(testURL).Get("/restconf/data/url").Reply(503).SetHeader("Retry-After", "10").BodyString(`{"errors":{"error":[{"error-tag":"in-use"}]}}`)
+	gock.New(testURL).Get("/restconf/data/url").Reply(200)
+	start := time.Now()
+	_, err := client.GetData("url")
+	assert.NoError(t, err)
+	// capped by the maximum delay
+	assert.GreaterOrEqual(t, time.Since(start), time.Second)
+	assert.Less(t, time.Since(start), 5*time.Second)
+}
+
+// TestTooManyRequests tests that 429 responses are retried, also without RESTCONF errors.
+func TestTooManyRequests(t *testing.T) {
+	defer gock.Off()
+	client := testClient()
+	client.MaxRetries = 1
+	client.BackoffMinDelay = 0
+	client.BackoffMaxDelay = 0
+
+	gock.New(testURL).Get("/restconf/data/url").Reply(429).SetHeader("Retry-After", "1")
+	gock.New(testURL).Get("/restconf/data/url").Reply(200)
+	res, err := client.GetData("url")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, res.Attempts)
+
+	client.MaxRetries = 0
+	gock.New(testURL).Get("/restconf/data/url").Reply(429)
+	_, err = client.GetData("url")
+	assert.ErrorContains(t, err, "transient rule: status-code=429")
+}
+
+// TestClientClone tests the Client::Clone method.
+func TestClientClone(t *testing.T) {
+	client, _ := NewClient(testURL, "usr", "pwd", true, SkipDiscovery("/restconf", true), MaxRetries(3), WithDefaultQuery("content", "config"))
+	clone, err := client.Clone(MaxRetries(5), WithDefaultQuery("depth", "1"), WithProxy("http://proxy:3128"))
+	assert.NoError(t, err)
+	assert.Equal(t, 3, client.MaxRetries)
+	assert.Equal(t, 5, clone.MaxRetries)
+	assert.Equal(t, "", client.DefaultQuery.Get("depth"))
+	assert.Equal(t, "config", clone.DefaultQuery.Get("content"))
+	assert.True(t, clone.DiscoveryComplete)
+	assert.True(t, clone.YangPatchCapability)
+	assert.NotSame(t, client.HttpClient.Jar, clone.HttpClient.Jar)
+	assert.NotSame(t, client.HttpClient.Transport, clone.HttpClient.Transport)
+	assert.Nil(t, client.HttpClient.Transport.(*http.Transport).Proxy)
+
+	clone, _ = client.Clone(func(c *Client) { c.Url = "https://10.0.0.2" })
+	assert.False(t, clone.DiscoveryComplete)
+
+	_, err = client.Clone(WithProxy("invalid"))
+	assert.Error(t, err)
+}
+
+// overlapWriter records whether writes overlap.
+type overlapWriter struct {
+	writing int32
+	overlap int32
+}
+
+func (w *overlapWriter) Write(p []byte) (int, error) {
+	if !atomic.CompareAndSwapInt32(&w.writing, 0, 1) {
+		atomic.StoreInt32(&w.overlap, 1)
+		return len(p), nil
+	}
+	time.Sleep(time.Millisecond)
+	atomic.StoreInt32(&w.writing, 0)
+	return len(p), nil
+}
+
+// TestClientCloneJSONLogging tests that the JSON log events of a client and its clone do not overlap.
+func TestClientCloneJSONLogging(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	writer := &overlapWriter{}
+	client, _ := NewClient(server.URL, "usr", "pwd", true, SkipDiscovery("/restconf", false), WithJSONLogging(writer))
+	clone, _ := client.Clone()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		for _, c := range []*Client{client, clone} {
+			wg.Add(1)
+			go func(c *Client) {
+				defer wg.Done()
+				c.GetData("url")
+			}(c)
+		}
+	}
+	wg.Wait()
+	assert.Equal(t, int32(0), atomic.LoadInt32(&writer.overlap))
+}
+
+// TestIgnoreNotFound tests the IgnoreNotFound request modifier.
+func TestIgnoreNotFound(t *testing.T) {
+	defer gock.Off()
+	client := testClient()
+
+	gock.New(testURL).Delete("/restconf/data/url").Reply(404).BodyString(`{"errors":{"error":[{"error-type":"application","error-tag":"data-missing"}]}}`)
+	res, err := client.DeleteData("url", IgnoreNotFound())
+	assert.NoError(t, err)
+	assert.Equal(t, 404, res.StatusCode)
+
+	gock.New(testURL).Delete("/restconf/data/url").Reply(404)
+	_, err = client.DeleteData("url")
+	assert.Error(t, err)
+}
+
+// TestMatchTransientError tests the matchTransientError function.
+func TestMatchTransientError(t *testing.T) {
+	assert.True(t, matchTransientError("lock-denied", "lock-denied", false))
+	assert.True(t, matchTransientError("lock", "lock-denied", false))
+	assert.False(t, matchTransientError("lock", "lock-denied", true))
+	assert.True(t, matchTransientError("failed (try again)", "failed (try again)", true))
+	assert.False(t, matchTransientError("failed (try again)", "failed (try again)", false))
+}
@@ -1,8 +1,13 @@
 package restconf
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/tidwall/gjson"
 	"github.com/tidwall/sjson"
@@ -27,6 +32,27 @@ type YangPatchEditModel struct {
 	Value     json.RawMessage `json:"value,omitempty"`
 }
 
+// BodyEncoder builds the string representation of a Body.
+type BodyEncoder interface {
+	// Set sets a path of the body to a value.
+	Set(body, path string, value interface{}) (string, error)
+	// SetRaw sets a path of the body to a raw, already encoded value.
+	SetRaw(body, path, rawValue string) (string, error)
+}
+
+// JSONBodyEncoder is the default BodyEncoder, which uses SJSON to build JSON bodies.
+type JSONBodyEncoder struct{}
+
+// Set sets a JSON path to a value.
+func (JSONBodyEncoder) Set(body, path string, value interface{}) (string, error) {
+	return sjson.Set(body, path, value)
+}
+
+// SetRaw sets a JSON path to a raw string value.
+func (JSONBodyEncoder) SetRaw(body, path, rawValue string) (string, error) {
+	return sjson.SetRaw(body, path, rawValue)
+}
+
 // Body wraps SJSON for building JSON body strings.
 // Usage example:
 //
@@ -35,9 +61,16 @@ type Body struct {
 	Str string
 }
 
+// Encoder returns the body wrapped in an EncodedBody, which uses the given BodyEncoder for subsequent modifications, e.g.
+//
+//	body := Body{}.Encoder(encoder).Set("Cisco-IOS-XE-native:native.hostname", "ROUTER-1").Body
+func (body Body) Encoder(encoder BodyEncoder) EncodedBody {
+	return EncodedBody{Body: body, BodyEncoder: encoder}
+}
+
 // Set sets a JSON path to a value.
 func (body Body) Set(path string, value interface{}) Body {
-	res, _ := sjson.Set(body.Str, path, value)
+	res, _ := JSONBodyEncoder{}.Set(body.Str, path, value)
 	body.Str = res
 	return body
 }
@@ -47,11 +80,59 @@ func (body Body) Set(path string, value interface{}) Body {
 //
 //	Body{}.SetRaw("Cisco-IOS-XE-native:native", Body{}.Set("hostname", "ROUTER-1").Str).Str
 func (body Body) SetRaw(path, rawValue string) Body {
-	res, _ := sjson.SetRaw(body.Str, path, rawValue)
+	res, _ := JSONBodyEncoder{}.SetRaw(body.Str, path, rawValue)
 	body.Str = res
 	return body
 }
 
+// EncodedBody is a Body built by a custom BodyEncoder, see Body.Encoder.
+type EncodedBody struct {
+	Body
+	BodyEncoder BodyEncoder
+}
+
+// Set sets a path of the body to a value using the BodyEncoder.
+func (body EncodedBody) Set(path string, value interface{}) EncodedBody {
+	res, _ := body.BodyEncoder.Set(body.Str, path, value)
+	body.Str = res
+	return body
+}
+
+// SetRaw sets a path of the body to a raw string value using the BodyEncoder.
+func (body EncodedBody) SetRaw(path, rawValue string) EncodedBody {
+	res, _ := body.BodyEncoder.SetRaw(body.Str, path, rawValue)
+	body.Str = res
+	return body
+}
+
+// Validate performs basic structural checks of the body before it is sent.
+// The body must be valid JSON consisting of a single top-level object member,
+// whose name is qualified with a module name, e.g. "Cisco-IOS-XE-native:hostname".
+func (body Body) Validate() error {
+	if strings.TrimSpace(body.Str) == "" {
+		return fmt.Errorf("Invalid body: empty")
+	}
+	if !gjson.Valid(body.Str) {
+		return fmt.Errorf("Invalid body: invalid JSON: %s", body.Str)
+	}
+	root := gjson.Parse(body.Str)
+	if !root.IsObject() {
+		return fmt.Errorf("Invalid body: not a JSON object: %s", body.Str)
+	}
+	var keys []string
+	root.ForEach(func(key, _ gjson.Result) bool {
+		keys = append(keys, key.String())
+		return true
+	})
+	if len(keys) != 1 {
+		return fmt.Errorf("Invalid body: expected a single top-level member, found %v", len(keys))
+	}
+	if !strings.Contains(keys[0], ":") {
+		return fmt.Errorf("Invalid body: top-level member %q is not qualified with a module name", keys[0])
+	}
+	return nil
+}
+
 // Res creates a Res object, i.e. a GJSON result object.
 func (body Body) Res() Res {
 	return Res{Res: gjson.Parse(body.Str)}
@@ -61,6 +142,20 @@ func (body Body) Res() Res {
 type Req struct {
 	// HttpReq is the *http.Request object.
 	HttpReq *http.Request
+	// Disable retries for this request
+	noRetry bool
+	// Datastore targeted instead of the data resource
+	datastore string
+	// Do not return an error if the resource does not exist
+	ignoreNotFound bool
+	// Do not add the default query parameters of the client
+	noDefaultQuery bool
+	// Label for correlation in logs and errors, not sent to the device
+	tag string
+	// Requested with-defaults mode, checked against the capabilities
+	withDefaults string
+	// Error of a request modifier, returned by Client.Do without sending the request
+	err error
 }
 
 // Query sets an HTTP query parameter.
@@ -79,3 +174,406 @@ func Query(k, v string) func(req *Req) {
 		req.HttpReq.URL.RawQuery = q.Encode()
 	}
 }
+
+// NoDefaultQuery omits the default query parameters of the client (see WithDefaultQuery), e.g. for write operations.
+//
+//	client.PutData("Cisco-IOS-XE-native:native/hostname", body, restconf.NoDefaultQuery())
+func NoDefaultQuery() func(req *Req) {
+	return func(req *Req) {
+		req.noDefaultQuery = true
+	}
+}
+
+// FieldsExpr builds the value of the fields query parameter (RFC 8040, section 4.8.3), e.g.
+//
+//	f := restconf.Fields("hostname")
+//	f.Child("interface").Leaf("name").Leaf("enabled")
+//	client.GetData("Cisco-IOS-XE-native:native", f.Query())
+//
+// requests "hostname;interface(name;enabled)".
+type FieldsExpr struct {
+	name     string
+	parent   *FieldsExpr
+	children []*FieldsExpr
+}
+
+// Fields creates a fields expression selecting the given nodes.
+func Fields(nodes ...string) *FieldsExpr {
+	f := &FieldsExpr{}
+	for _, node := range nodes {
+		f.Leaf(node)
+	}
+	return f
+}
+
+// Leaf selects a node below the current node and returns the current node.
+func (f *FieldsExpr) Leaf(name string) *FieldsExpr {
+	f.children = append(f.children, &FieldsExpr{name: name, parent: f})
+	return f
+}
+
+// Child selects a node below the current node and returns the selected node,
+// such that nodes below it can be selected.
+func (f *FieldsExpr) Child(name string) *FieldsExpr {
+	child := &FieldsExpr{name: name, parent: f}
+	f.children = append(f.children, child)
+	return child
+}
+
+// Parent returns the parent of the current node, or the current node if it is the root.
+func (f *FieldsExpr) Parent() *FieldsExpr {
+	if f.parent == nil {
+		return f
+	}
+	return f.parent
+}
+
+// String returns the fields expression of the whole tree, regardless of the current node.
+func (f *FieldsExpr) String() string {
+	root := f
+	for root.parent != nil {
+		root = root.parent
+	}
+	return root.selectors()
+}
+
+// render child nodes separated by semicolons
+func (f *FieldsExpr) selectors() string {
+	parts := make([]string, len(f.children))
+	for i, child := range f.children {
+		switch len(child.children) {
+		case 0:
+			parts[i] = child.name
+		case 1:
+			parts[i] = child.name + "/" + child.selectors()
+		default:
+			parts[i] = child.name + "(" + child.selectors() + ")"
+		}
+	}
+	return strings.Join(parts, ";")
+}
+
+// Query returns a request modifier setting the fields query parameter.
+func (f *FieldsExpr) Query() func(req *Req) {
+	return Query("fields", f.String())
+}
+
+// Values of the content query parameter (RFC 8040, section 4.8.1)
+const (
+	ContentConfig    = "config"
+	ContentNonConfig = "nonconfig"
+	ContentAll       = "all"
+)
+
+// Content selects whether configuration and/or non-configuration data is returned by a GET request, e.g.
+//
+//	client.GetData("Cisco-IOS-XE-native:native", restconf.Content(restconf.ContentConfig))
+//
+// If kind is not one of ContentConfig, ContentNonConfig or ContentAll, the request fails without being sent.
+func Content(kind string) func(req *Req) {
+	return func(req *Req) {
+		if kind != ContentConfig && kind != ContentNonConfig && kind != ContentAll {
+			req.err = fmt.Errorf("Invalid content %q: must be one of %q, %q or %q", kind, ContentConfig, ContentNonConfig, ContentAll)
+			return
+		}
+		setQuery(req, "content", kind)
+	}
+}
+
+// Modes of the with-defaults query parameter (RFC 6243, section 3)
+const (
+	DefaultsReportAll       = "report-all"
+	DefaultsTrim            = "trim"
+	DefaultsExplicit        = "explicit"
+	DefaultsReportAllTagged = "report-all-tagged"
+)
+
+// WithDefaults selects how default values are reported by a GET request (RFC 8040, section 4.8.9), e.g.
+//
+//	client.GetData("Cisco-IOS-XE-native:native", restconf.WithDefaults(restconf.DefaultsReportAll))
+//
+// If the mode is invalid or not supported according to the defaults capability of the device,
+// the request fails without being sent, see Client.WithDefaultsSupportedModes.
+func WithDefaults(mode string) func(req *Req) {
+	return func(req *Req) {
+		switch mode {
+		case DefaultsReportAll, DefaultsTrim, DefaultsExplicit, DefaultsReportAllTagged:
+		default:
+			req.err = fmt.Errorf("Invalid with-defaults mode %q", mode)
+			return
+		}
+		req.withDefaults = mode
+		setQuery(req, "with-defaults", mode)
+	}
+}
+
+// StartTime requests the replay of notifications of an event stream generated since t (RFC 8040, section 4.8.7), e.g.
+//
+//	client.Subscribe("NETCONF", restconf.StartTime(time.Now().Add(-time.Hour)))
+//
+// The time is sent in UTC.
+func StartTime(t time.Time) func(req *Req) {
+	return func(req *Req) {
+		setQuery(req, "start-time", t.UTC().Format(time.RFC3339Nano))
+	}
+}
+
+// StopTime limits the replay of notifications of an event stream to those generated before t (RFC 8040, section 4.8.8).
+// The time is sent in UTC.
+func StopTime(t time.Time) func(req *Req) {
+	return func(req *Req) {
+		setQuery(req, "stop-time", t.UTC().Format(time.RFC3339Nano))
+	}
+}
+
+// Filter selects the notifications of an event stream or the data returned by a GET request
+// matching the XPath expression (RFC 8040, section 4.8.4), e.g.
+//
+//	client.Subscribe("NETCONF", restconf.Filter("/ietf-netconf-notifications:netconf-config-change"))
+func Filter(xpath string) func(req *Req) {
+	return func(req *Req) {
+		setQuery(req, "filter", xpath)
+	}
+}
+
+// Depth limits the depth of subtrees returned by a GET request to n levels (RFC 8040, section 4.8.2), e.g.
+//
+//	client.GetData("Cisco-IOS-XE-native:native", restconf.Depth(2))
+//
+// If n is not within 1..65535, the request fails without being sent.
+func Depth(n int) func(req *Req) {
+	return func(req *Req) {
+		if n < 1 || n > 65535 {
+			req.err = fmt.Errorf("Invalid depth %d: must be between 1 and 65535", n)
+			return
+		}
+		setQuery(req, "depth", strconv.Itoa(n))
+	}
+}
+
+// DepthUnbounded requests subtrees of unlimited depth, which is the default of most devices.
+func DepthUnbounded() func(req *Req) {
+	return func(req *Req) {
+		setQuery(req, "depth", "unbounded")
+	}
+}
+
+// set a query parameter, replacing any previous values
+func setQuery(req *Req, k, v string) {
+	q := req.HttpReq.URL.Query()
+	q.Set(k, v)
+	req.HttpReq.URL.RawQuery = q.Encode()
+}
+
+// Context sets the context of the request, which can be used to cancel the request, e.g.
+//
+//	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+//	defer cancel()
+//	client.GetData("Cisco-IOS-XE-native:native", restconf.Context(ctx))
+func Context(ctx context.Context) func(req *Req) {
+	return func(req *Req) {
+		req.HttpReq = req.HttpReq.WithContext(ctx)
+	}
+}
+
+// NoRetry disables retries of the request, regardless of the retried methods of the client.
+//
+//	client.DeleteData("Cisco-IOS-XE-native:native/banner", restconf.NoRetry())
+func NoRetry() func(req *Req) {
+	return func(req *Req) {
+		req.noRetry = true
+	}
+}
+
+// IgnoreNotFound treats a 404 Not Found response as success, e.g. for idempotent deletes:
+//
+//	res, err := client.DeleteData("Cisco-IOS-XE-native:native/banner", restconf.IgnoreNotFound())
+//
+// The status code is available in Res.StatusCode.
+func IgnoreNotFound() func(req *Req) {
+	return func(req *Req) {
+		req.ignoreNotFound = true
+	}
+}
+
+// Datastores of the ietf-datastores model (RFC 8342)
+const (
+	DatastoreRunning     = "ietf-datastores:running"
+	DatastoreCandidate   = "ietf-datastores:candidate"
+	DatastoreStartup     = "ietf-datastores:startup"
+	DatastoreIntended    = "ietf-datastores:intended"
+	DatastoreOperational = "ietf-datastores:operational"
+)
+
+// Datastore targets a datastore resource of the Network Management Datastore Architecture (RFC 8527)
+// instead of the data resource, e.g. to stage changes in the candidate datastore:
+//
+//	client.PutData("Cisco-IOS-XE-native:native/hostname", body, restconf.Datastore(restconf.DatastoreCandidate))
+//
+// The request path "{+restconf}/data/<path>" becomes "{+restconf}/ds/<datastore>/<path>". See Client.Commit.
+func Datastore(name string) func(req *Req) {
+	return func(req *Req) {
+		req.datastore = name
+	}
+}
+
+// Tag attaches a label to the request, which is included in log messages and returned errors,
+// e.g. to correlate concurrent requests of the same operation. The tag is not sent to the device.
+//
+//	client.GetData("Cisco-IOS-XE-native:native", restconf.Tag("backup"))
+func Tag(name string) func(req *Req) {
+	return func(req *Req) {
+		req.tag = name
+	}
+}
+
+// key/value pairs of a request for structured logging, followed by additional key/value pairs
+func (req Req) logFields(keysAndValues ...interface{}) []interface{} {
+	fields := []interface{}{"method", req.HttpReq.Method, "url", req.HttpReq.URL.Redacted()}
+	if req.tag != "" {
+		fields = append(fields, "tag", req.tag)
+	}
+	return append(fields, keysAndValues...)
+}
+
+// log prefix of tagged requests
+func (req Req) logTag() string {
+	if req.tag == "" {
+		return ""
+	}
+	return "[" + req.tag + "] "
+}
+
+// InsertBefore inserts a new entry of an ordered-by-user list before the sibling entry with the given key, e.g.
+//
+//	client.PostData("Cisco-IOS-XE-acl:access-lists/acl=ACL1/aces", ace,
+//	  restconf.InsertBefore("Cisco-IOS-XE-acl:access-lists/acl=ACL1/aces/ace", "20"))
+func InsertBefore(listPath, siblingKey string) func(req *Req) {
+	return insertPoint("before", listPath, siblingKey)
+}
+
+// InsertAfter inserts a new entry of an ordered-by-user list after the sibling entry with the given key, e.g.
+//
+//	client.PostData("Cisco-IOS-XE-acl:access-lists/acl=ACL1/aces", ace,
+//	  restconf.InsertAfter("Cisco-IOS-XE-acl:access-lists/acl=ACL1/aces/ace", "10"))
+func InsertAfter(listPath, siblingKey string) func(req *Req) {
+	return insertPoint("after", listPath, siblingKey)
+}
+
+// set insert and point query parameters
+func insertPoint(where, listPath, siblingKey string) func(req *Req) {
+	return func(req *Req) {
+		Insert(where)(req)
+		setQuery(req, "point", pointPath(listPath, siblingKey))
+	}
+}
+
+// Insert sets where a new entry of an ordered-by-user list or leaf-list is inserted by a POST or PUT request
+// (RFC 8040, section 4.8.5), i.e. "first", "last", "before" or "after". The latter two require Point, e.g.
+//
+//	client.PutData("Cisco-IOS-XE-acl:access-lists/acl=ACL1/aces/ace=15", ace,
+//	  restconf.Insert("after"), restconf.Point("/Cisco-IOS-XE-acl:access-lists/acl=ACL1/aces/ace=10"))
+func Insert(where string) func(req *Req) {
+	return func(req *Req) {
+		switch where {
+		case "first", "last", "before", "after":
+		default:
+			req.err = fmt.Errorf("Invalid insert %q: must be one of \"first\", \"last\", \"before\" or \"after\"", where)
+			return
+		}
+		setQuery(req, "insert", where)
+	}
+}
+
+// Point sets the path of the list or leaf-list entry a new entry is inserted before or after (RFC 8040, section 4.8.6).
+// It requires Insert with "before" or "after".
+func Point(path string) func(req *Req) {
+	return func(req *Req) {
+		if !strings.HasPrefix(path, "/") {
+			path = "/" + path
+		}
+		setQuery(req, "point", path)
+	}
+}
+
+// check that the point query parameter is used if and only if inserting before or after an entry
+func (req Req) checkInsertPoint() error {
+	q := req.HttpReq.URL.Query()
+	where := q.Get("insert")
+	relative := where == "before" || where == "after"
+	if _, ok := q["point"]; ok && !relative {
+		return fmt.Errorf("Invalid point: requires insert \"before\" or \"after\"")
+	}
+	if relative && q.Get("point") == "" {
+		return fmt.Errorf("Invalid insert %q: requires point", where)
+	}
+	return nil
+}
+
+// build point resource path of a list entry
+func pointPath(listPath, key string) string {
+	if !strings.HasPrefix(listPath, "/") {
+		listPath = "/" + listPath
+	}
+	return listPath + "=" + encodeKey(key)
+}
+
+// CompositeKey builds the path segment of a list entry identified by one or more keys, e.g.
+//
+//	restconf.CompositeKey("route", "10.0.0.0/8", "Vlan 10")
+//
+// returns "route=10.0.0.0%2F8,Vlan%2010". Each key is percent-encoded individually,
+// such that commas and slashes within keys are not confused with separators (RFC 8040, section 3.5.3).
+func CompositeKey(listName string, keys ...string) string {
+	encoded := make([]string, len(keys))
+	for i, key := range keys {
+		encoded[i] = encodeKey(key)
+	}
+	return listName + "=" + strings.Join(encoded, ",")
+}
+
+// percent-encode all characters of a key value except unreserved characters
+func encodeKey(key string) string {
+	var b strings.Builder
+	for i := 0; i < len(key); i++ {
+		c := key[i]
+		if ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || ('0' <= c && c <= '9') || c == '-' || c == '.' || c == '_' || c == '~' {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// IfModifiedSince makes a GET request conditional on the resource being modified after t.
+// If the resource has not been modified, the response has Res.NotModified set and no error is returned.
+// The time of the last modification is returned in the "Last-Modified" response header, e.g.
+//
+//	res, _ := client.GetData("Cisco-IOS-XE-native:native", restconf.IfModifiedSince(lastModified))
+//	lastModified, _ = http.ParseTime(res.Header.Get("Last-Modified"))
+func IfModifiedSince(t time.Time) func(req *Req) {
+	return func(req *Req) {
+		req.HttpReq.Header.Set("If-Modified-Since", t.UTC().Format(http.TimeFormat))
+	}
+}
+
+// IfMatch makes an edit conditional on the entity tag of the resource, e.g.
+//
+//	res, _ := client.GetData("Cisco-IOS-XE-native:native/hostname")
+//	_, err := client.PutData("Cisco-IOS-XE-native:native/hostname", body, restconf.IfMatch(res.ETag))
+//
+// If the resource has been modified since, the request fails with ErrPreconditionFailed.
+func IfMatch(tag string) func(req *Req) {
+	return func(req *Req) {
+		req.HttpReq.Header.Set("If-Match", tag)
+	}
+}
+
+// IfUnmodifiedSince makes an edit conditional on the resource not being modified since the given time.
+// If the resource has been modified since, the request fails with ErrPreconditionFailed.
+func IfUnmodifiedSince(t time.Time) func(req *Req) {
+	return func(req *Req) {
+		req.HttpReq.Header.Set("If-Unmodified-Since", t.UTC().Format(http.TimeFormat))
+	}
+}
@@ -2,8 +2,10 @@ package restconf
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/tidwall/gjson"
 	"gopkg.in/h2non/gock.v1"
 )
 
@@ -27,3 +29,166 @@ func TestQuery(t *testing.T) {
 	_, err = client.GetData("/url", Query("foo", "bar,baz"))
 	assert.NoError(t, err)
 }
+
+// TestBodyValidate tests the Body::Validate method.
+func TestBodyValidate(t *testing.T) {
+	assert.NoError(t, Body{}.Set("Cisco-IOS-XE-native:hostname", "R1").Validate())
+	assert.Error(t, Body{}.Validate())
+	assert.Error(t, Body{Str: `{"a:b":`}.Validate())
+	assert.Error(t, Body{Str: `{}`}.Validate())
+	assert.Error(t, Body{Str: `[1]`}.Validate())
+	assert.Error(t, Body{}.Set("hostname", "R1").Validate())
+	assert.Error(t, Body{}.Set("a:b", 1).Set("a:c", 2).Validate())
+}
+
+// TestInsertAfter tests the InsertAfter and InsertBefore functions.
+func TestInsertAfter(t *testing.T) {
+	client, _ := NewClient(testURL, "usr", "pwd", true, SkipDiscovery("/restconf", false))
+	req := client.NewReq("POST", "/data/a:acl=1/aces", nil, InsertAfter("a:acl=1/aces/ace", "1/0"))
+	assert.Equal(t, "after", req.HttpReq.URL.Query().Get("insert"))
+	assert.Equal(t, "/a:acl=1/aces/ace=1%2F0", req.HttpReq.URL.Query().Get("point"))
+
+	req = client.NewReq("POST", "/data/a:acl=1/aces", nil, InsertBefore("/a:acl=1/aces/ace", "10"))
+	assert.Equal(t, "before", req.HttpReq.URL.Query().Get("insert"))
+	assert.Equal(t, "/a:acl=1/aces/ace=10", req.HttpReq.URL.Query().Get("point"))
+}
+
+// TestIfModifiedSince tests the IfModifiedSince function.
+func TestIfModifiedSince(t *testing.T) {
+	defer gock.Off()
+	client := testClient()
+
+	gock.New(testURL).Get("/restconf/data/url").MatchHeader("If-Modified-Since", "Mon, 02 Jan 2023 03:04:05 GMT").Reply(304)
+	res, err := client.GetData("url", IfModifiedSince(time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)))
+	assert.NoError(t, err)
+	assert.True(t, res.NotModified)
+}
+
+// TestIfMatch tests the IfMatch and IfUnmodifiedSince modifiers.
+func TestIfMatch(t *testing.T) {
+	defer gock.Off()
+	client := testClient()
+
+	gock.New(testURL).Get("/restconf/data/url").Reply(200).SetHeader("ETag", `"abc"`).BodyString(`{"a":"b"}`)
+	res, _ := client.GetData("url")
+	assert.Equal(t, `"abc"`, res.ETag)
+
+	gock.New(testURL).Put("/restconf/data/url").MatchHeader("If-Match", `"abc"`).Reply(412)
+	_, err := client.PutData("url", `{"a":"c"}`, IfMatch(res.ETag))
+	assert.ErrorIs(t, err, ErrPreconditionFailed)
+
+	gock.New(testURL).Put("/restconf/data/url").MatchHeader("If-Unmodified-Since", "Mon, 02 Jan 2023 03:04:05 GMT").Reply(204)
+	_, err = client.PutData("url", `{"a":"c"}`, IfUnmodifiedSince(time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)))
+	assert.NoError(t, err)
+}
+
+type testBodyEncoder struct{}
+
+func (testBodyEncoder) Set(body, path string, value interface{}) (string, error) {
+	return body + path, nil
+}
+
+func (testBodyEncoder) SetRaw(body, path, rawValue string) (string, error) {
+	return body + rawValue, nil
+}
+
+// TestBodyEncoder tests the Body::Encoder method.
+func TestBodyEncoder(t *testing.T) {
+	body := Body{}.Encoder(testBodyEncoder{}).Set("a", 1).SetRaw("b", "c")
+	assert.Equal(t, "ac", body.Str)
+	assert.Equal(t, Body{"ac"}, body.Body)
+}
+
+// TestCompositeKey tests the CompositeKey function.
+func TestCompositeKey(t *testing.T) {
+	assert.Equal(t, "interface=GigabitEthernet1", CompositeKey("interface", "GigabitEthernet1"))
+	assert.Equal(t, "route=10.0.0.0%2F8,Vlan%2010", CompositeKey("route", "10.0.0.0/8", "Vlan 10"))
+	assert.Equal(t, "entry=a%2Cb,c", CompositeKey("entry", "a,b", "c"))
+	assert.Equal(t, "entry=2001%3Adb8%3A%3A1,", CompositeKey("entry", "2001:db8::1", ""))
+
+	// Keys are preserved in the request URL
+	client, _ := NewClient(testURL, "usr", "pwd", true, SkipDiscovery("/restconf", false))
+	req := client.NewReq("GET", "/data/a:routes/"+CompositeKey("route", "10.0.0.0/8", "a,b"), nil)
+	assert.Equal(t, "/restconf/data/a:routes/route=10.0.0.0%2F8,a%2Cb", req.HttpReq.URL.EscapedPath())
+}
+
+// TestFields tests the Fields function.
+func TestFields(t *testing.T) {
+	f := Fields("hostname")
+	f.Child("interface").Leaf("name").Leaf("enabled")
+	assert.Equal(t, "hostname;interface(name;enabled)", f.String())
+
+	f = Fields().Child("interface").Child("ipv4").Leaf("address").Parent().Leaf("name").Parent().Leaf("hostname")
+	assert.Equal(t, "interface(ipv4/address;name);hostname", f.String())
+
+	client := testClient()
+	req := client.NewReq("GET", "/data/url", nil, Fields("a", "b/c").Query())
+	assert.Equal(t, "a;b/c", req.HttpReq.URL.Query().Get("fields"))
+}
+
+// TestDepth tests the Depth and DepthUnbounded functions.
+func TestDepth(t *testing.T) {
+	defer gock.Off()
+	client := testClient()
+
+	req := client.NewReq("GET", "/data/url", nil, Depth(3))
+	assert.Equal(t, "3", req.HttpReq.URL.Query().Get("depth"))
+
+	req = client.NewReq("GET", "/data/url", nil, Depth(1), DepthUnbounded())
+	assert.Equal(t, []string{"unbounded"}, req.HttpReq.URL.Query()["depth"])
+
+	_, err := client.GetData("url", Depth(0))
+	assert.ErrorContains(t, err, "Invalid depth 0")
+	_, err = client.GetData("url", Depth(65536))
+	assert.ErrorContains(t, err, "Invalid depth 65536")
+	_, _, _, err = client.DoRawBytes("GET", "/data/url", nil, Depth(0))
+	assert.ErrorContains(t, err, "Invalid depth 0")
+	err = client.GetDataArrayStream("url", "a", func(gjson.Result) bool { return true }, Depth(0))
+	assert.ErrorContains(t, err, "Invalid depth 0")
+}
+
+// TestContent tests the Content function.
+func TestContent(t *testing.T) {
+	defer gock.Off()
+	client := testClient()
+
+	req := client.NewReq("GET", "/data/url", nil, Content(ContentNonConfig))
+	assert.Equal(t, "nonconfig", req.HttpReq.URL.Query().Get("content"))
+
+	_, err := client.GetData("url", Content("configuration"))
+	assert.ErrorContains(t, err, `Invalid content "configuration"`)
+}
+
+// TestStartStopTime tests the StartTime, StopTime and Filter functions.
+func TestStartStopTime(t *testing.T) {
+	client := testClient()
+	start := time.Date(2022, 3, 1, 14, 30, 0, 0, time.FixedZone("CET", 3600))
+	stop := time.Date(2022, 3, 1, 14, 30, 0, 500000000, time.UTC)
+
+	req := client.NewReq("GET", "/streams/NETCONF", nil, StartTime(start), StopTime(stop), Filter("/a:b"))
+	assert.Equal(t, "2022-03-01T13:30:00Z", req.HttpReq.URL.Query().Get("start-time"))
+	assert.Equal(t, "2022-03-01T14:30:00.5Z", req.HttpReq.URL.Query().Get("stop-time"))
+	assert.Equal(t, "/a:b", req.HttpReq.URL.Query().Get("filter"))
+}
+
+// TestInsertPoint tests the Insert and Point functions.
+func TestInsertPoint(t *testing.T) {
+	defer gock.Off()
+	client := testClient()
+
+	req := client.NewReq("PUT", "/data/a:acl=1/aces/ace=15", nil, Insert("after"), Point("a:acl=1/aces/ace=10"))
+	assert.Equal(t, "after", req.HttpReq.URL.Query().Get("insert"))
+	assert.Equal(t, "/a:acl=1/aces/ace=10", req.HttpReq.URL.Query().Get("point"))
+	assert.NoError(t, req.checkInsertPoint())
+
+	gock.New(testURL).Put("/restconf/data/a:acl=1/aces/ace=15").MatchParam("insert", "first").Reply(204)
+	_, err := client.PutData("a:acl=1/aces/ace=15", "{}", Insert("first"))
+	assert.NoError(t, err)
+
+	_, err = client.PutData("a:acl=1/aces/ace=15", "{}", Insert("middle"))
+	assert.ErrorContains(t, err, `Invalid insert "middle"`)
+	_, err = client.PutData("a:acl=1/aces/ace=15", "{}", Insert("before"))
+	assert.ErrorContains(t, err, `Invalid insert "before": requires point`)
+	_, err = client.PutData("a:acl=1/aces/ace=15", "{}", Insert("last"), Point("a:acl=1/aces/ace=10"))
+	assert.ErrorContains(t, err, "Invalid point")
+}
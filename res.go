@@ -1,6 +1,16 @@
 package restconf
 
 import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
 	"github.com/tidwall/gjson"
 )
 
@@ -13,16 +23,118 @@ type ErrorsRootModel struct {
 }
 
 type ErrorsModel struct {
-	Error []ErrorModel `json:"error"`
+	Error []ErrorModel `json:"error" xml:"error"`
 }
 
 type ErrorModel struct {
-	ErrorType    string `json:"error-type"`
-	ErrorTag     string `json:"error-tag"`
-	ErrorAppTag  string `json:"error-app-tag,omitempty"`
-	ErrorPath    string `json:"error-path,omitempty"`
-	ErrorMessage string `json:"error-message,omitempty"`
-	ErrorInfo    string `json:"error-info,omitempty"`
+	ErrorType    string `json:"error-type" xml:"error-type"`
+	ErrorTag     string `json:"error-tag" xml:"error-tag"`
+	ErrorAppTag  string `json:"error-app-tag,omitempty" xml:"error-app-tag"`
+	ErrorPath    string `json:"error-path,omitempty" xml:"error-path"`
+	ErrorMessage string `json:"error-message,omitempty" xml:"error-message"`
+	ErrorInfo    string `json:"error-info,omitempty" xml:"error-info"`
+}
+
+// UnmarshalJSON decodes an error, keeping structured error-info content as raw JSON text.
+func (e *ErrorModel) UnmarshalJSON(data []byte) error {
+	type errorModel ErrorModel
+	aux := struct {
+		*errorModel
+		ErrorInfo json.RawMessage `json:"error-info,omitempty"`
+	}{errorModel: (*errorModel)(e)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	info := gjson.ParseBytes(aux.ErrorInfo)
+	if info.Type == gjson.String {
+		e.ErrorInfo = info.String()
+	} else if info.Exists() && info.Type != gjson.Null {
+		e.ErrorInfo = info.Raw
+	} else {
+		e.ErrorInfo = ""
+	}
+	return nil
+}
+
+// ErrorInfoJSON returns the error-info content as a GJSON result, e.g.
+//
+//	badElement := res.Errors.Error[0].ErrorInfoJSON().Get("bad-element").String()
+//
+// Structured content is returned as an object or array, any other content as a string.
+func (e ErrorModel) ErrorInfoJSON() gjson.Result {
+	info := strings.TrimSpace(e.ErrorInfo)
+	if (strings.HasPrefix(info, "{") || strings.HasPrefix(info, "[")) && gjson.Valid(info) {
+		return gjson.Parse(info)
+	}
+	quoted, _ := json.Marshal(e.ErrorInfo)
+	return gjson.ParseBytes(quoted)
+}
+
+// RestconfError is returned if a request fails with an HTTP error status code or RESTCONF errors, e.g.
+//
+//	var rcErr *restconf.RestconfError
+//	if errors.As(err, &rcErr) && rcErr.StatusCode == 409 {
+//	    ...
+//	}
+type RestconfError struct {
+	// HTTP response status code
+	StatusCode      int
+	Errors          ErrorsModel
+	YangPatchStatus YangPatchStatusModel
+	// Transient error rule matched by the response, if retries were exhausted
+	TransientError *TransientError
+}
+
+// ErrPreconditionFailed matches the error of a request failing with 412 Precondition Failed,
+// e.g. because the resource was modified since it was read, see IfMatch and IfUnmodifiedSince.
+//
+//	if errors.Is(err, restconf.ErrPreconditionFailed) {
+//	    ...
+//	}
+var ErrPreconditionFailed = errors.New("Precondition failed")
+
+func newRestconfError(res Res, transientError *TransientError) *RestconfError {
+	return &RestconfError{
+		StatusCode:      res.StatusCode,
+		Errors:          res.Errors,
+		YangPatchStatus: res.YangPatchStatus,
+		TransientError:  transientError,
+	}
+}
+
+// Is returns true if the target is ErrPreconditionFailed and the status code is 412.
+func (e *RestconfError) Is(target error) bool {
+	return target == ErrPreconditionFailed && e.StatusCode == http.StatusPreconditionFailed
+}
+
+func (e *RestconfError) Error() string {
+	if e.StatusCode >= 200 && e.StatusCode <= 299 {
+		return fmt.Sprintf("RESTCONF Request failed: %+v %+v", e.Errors, e.YangPatchStatus)
+	}
+	msg := fmt.Sprintf("HTTP Request failed: StatusCode %v, RESTCONF errors %+v %+v", e.StatusCode, e.Errors, e.YangPatchStatus)
+	if e.TransientError != nil {
+		msg += fmt.Sprintf(", transient rule: %s", e.TransientError)
+	}
+	return msg
+}
+
+// BatchError is returned by Client.GetDataBatch if one or more requests fail.
+// Each error is prefixed with the path of the failed request.
+type BatchError struct {
+	Errors []error
+}
+
+func (e *BatchError) Error() string {
+	msgs := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// Unwrap returns the errors of the failed requests.
+func (e *BatchError) Unwrap() []error {
+	return e.Errors
 }
 
 type YangPatchStatusRootModel struct {
@@ -30,25 +142,46 @@ type YangPatchStatusRootModel struct {
 }
 
 type YangPatchStatusModel struct {
-	PatchId      string                           `json:"patch-id"`
-	GlobalStatus YangPatchStatusGlobalStatusModel `json:"global-status,omitempty"`
-	EditStatus   YangPatchStatusEditStatusModel   `json:"edit-status,omitempty"`
-	Errors       ErrorsModel                      `json:"errors,omitempty"`
+	PatchId      string                           `json:"patch-id" xml:"patch-id"`
+	GlobalStatus YangPatchStatusGlobalStatusModel `json:"global-status,omitempty" xml:"global-status"`
+	EditStatus   YangPatchStatusEditStatusModel   `json:"edit-status,omitempty" xml:"edit-status"`
+	Errors       ErrorsModel                      `json:"errors,omitempty" xml:"errors"`
 }
 
 type YangPatchStatusGlobalStatusModel struct {
-	Ok     bool        `json:"ok"`
-	Errors ErrorsModel `json:"errors"`
+	Ok     EmptyLeaf   `json:"ok" xml:"ok"`
+	Errors ErrorsModel `json:"errors" xml:"errors"`
 }
 
 type YangPatchStatusEditStatusModel struct {
-	Edit []YangPatchStatusEditStatusEditModel `json:"edit"`
+	Edit []YangPatchStatusEditStatusEditModel `json:"edit" xml:"edit"`
 }
 
 type YangPatchStatusEditStatusEditModel struct {
-	EditId string      `json:"edit-id"`
-	Ok     bool        `json:"ok"`
-	Errors ErrorsModel `json:"errors"`
+	EditId string      `json:"edit-id" xml:"edit-id"`
+	Ok     EmptyLeaf   `json:"ok" xml:"ok"`
+	Errors ErrorsModel `json:"errors" xml:"errors"`
+}
+
+// EmptyLeaf is a YANG leaf of type empty, which is encoded as [null] in JSON.
+// It is true if the leaf is present.
+type EmptyLeaf bool
+
+// UnmarshalJSON decodes an empty leaf, accepting boolean values as well.
+func (e *EmptyLeaf) UnmarshalJSON(data []byte) error {
+	var b bool
+	if err := json.Unmarshal(data, &b); err == nil {
+		*e = EmptyLeaf(b)
+		return nil
+	}
+	*e = true
+	return nil
+}
+
+// UnmarshalXML decodes an empty leaf, which is true if the element is present.
+func (e *EmptyLeaf) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	*e = true
+	return d.Skip()
 }
 
 type CapabilitiesRootModel struct {
@@ -59,13 +192,335 @@ type CapabilitiesModel struct {
 	Capability []string `json:"capability"`
 }
 
+// DatastoreModel is a datastore of the ietf-netconf-monitoring model.
+type DatastoreModel struct {
+	Name  string              `json:"name"`
+	Locks DatastoreLocksModel `json:"locks"`
+}
+
+type DatastoreLocksModel struct {
+	GlobalLock  *DatastoreGlobalLockModel   `json:"global-lock,omitempty"`
+	PartialLock []DatastorePartialLockModel `json:"partial-lock,omitempty"`
+}
+
+type DatastoreGlobalLockModel struct {
+	LockedBySession int    `json:"locked-by-session"`
+	LockedTime      string `json:"locked-time"`
+}
+
+type DatastorePartialLockModel struct {
+	LockId          int      `json:"lock-id"`
+	LockedBySession int      `json:"locked-by-session"`
+	LockedTime      string   `json:"locked-time"`
+	Select          []string `json:"select"`
+	LockedNode      []string `json:"locked-node"`
+}
+
+// Module is a YANG module implemented by the device, as listed by the ietf-yang-library model.
+type Module struct {
+	Name      string   `json:"name"`
+	Revision  string   `json:"revision"`
+	Namespace string   `json:"namespace"`
+	Feature   []string `json:"feature"`
+	// URL of the YANG source of the module (RFC 7895)
+	Schema string `json:"schema,omitempty"`
+	// URLs of the YANG source of the module (RFC 8525)
+	Location []string `json:"location,omitempty"`
+}
+
 // Res is an API response returned by client requests.
 // Res.Res is a GJSON result, which offers advanced and safe parsing capabilities.
 // https://github.com/tidwall/gjson
 type Res struct {
 	Res gjson.Result
 	// HTTP response status code
-	StatusCode      int
+	StatusCode int
+	// HTTP response headers
+	Header          http.Header
 	Errors          ErrorsModel
 	YangPatchStatus YangPatchStatusModel
+	// Entity tag of the ETag response header, see IfMatch
+	ETag string
+	// Number of attempts made by Client.Do, i.e. 1 if the request was not retried
+	Attempts int
+	// URL of the Location response header, e.g. of a created resource, see LocationPath
+	Location string
+	// Location relative to the data resource
+	locationPath string
+	// HTTP methods of the Allow response header, e.g. of an OPTIONS request
+	AllowedMethods []string
+	// Value of the Deprecation response header, if any
+	Deprecation string
+	// Value of the Sunset response header, if any
+	Sunset string
+	// True if the resource has not been modified (304 Not Modified)
+	NotModified bool
+	// Paths of resources created by a YANG-Patch request
+	createdPaths []string
+}
+
+// Pretty returns the response body as indented JSON, e.g. for logging.
+// The raw response body in Res.Res.Raw is not modified.
+func (res Res) Pretty() string {
+	if !res.HasBody() {
+		return ""
+	}
+	return res.Res.Get("@pretty").Raw
+}
+
+// LocationPath returns the path of the Location response header relative to the data resource, e.g.
+//
+//	res, _ := client.PostData("Cisco-IOS-XE-native:native", user)
+//	res, _ = client.GetData(res.LocationPath()) // "Cisco-IOS-XE-native:native/username=test-user"
+//
+// An empty string is returned if the response has no Location header within the data resource.
+func (res Res) LocationPath() string {
+	return res.locationPath
+}
+
+// HasBody returns true if the response has a non-empty body.
+func (res Res) HasBody() bool {
+	return res.Res.Raw != ""
+}
+
+// IsSuccess returns true if the HTTP status code is 2xx.
+func (res Res) IsSuccess() bool {
+	return res.StatusCode >= 200 && res.StatusCode <= 299
+}
+
+// IsClientError returns true if the HTTP status code is 4xx.
+func (res Res) IsClientError() bool {
+	return res.StatusCode >= 400 && res.StatusCode <= 499
+}
+
+// IsServerError returns true if the HTTP status code is 5xx.
+func (res Res) IsServerError() bool {
+	return res.StatusCode >= 500 && res.StatusCode <= 599
+}
+
+// CreatedPaths returns the data resource paths created by the successful
+// "create" and "insert" edits of a YANG-Patch request, e.g.
+// "Cisco-IOS-XE-native:native/interface/Loopback=1".
+// The paths can be used for subsequent requests, e.g. with GetData().
+func (res Res) CreatedPaths() []string {
+	return res.createdPaths
+}
+
+// Notification is a RESTCONF (RFC 8040) event notification.
+type Notification struct {
+	// Time the event was generated by the event source
+	EventTime time.Time
+	// Module-qualified name of the event, e.g. "ietf-netconf-notifications:netconf-config-change"
+	Type string
+	// Event content
+	Data gjson.Result
+}
+
+// ParseNotification parses a JSON encoded "ietf-restconf:notification" envelope.
+func ParseNotification(data []byte) (Notification, error) {
+	n := Notification{}
+	envelope := gjson.GetBytes(data, "ietf-restconf:notification")
+	if !envelope.IsObject() {
+		return n, fmt.Errorf("Invalid notification, missing ietf-restconf:notification envelope: %s", string(data))
+	}
+	var err error
+	envelope.ForEach(func(key, value gjson.Result) bool {
+		if key.String() == "eventTime" {
+			n.EventTime, err = time.Parse(time.RFC3339, value.String())
+			if err != nil {
+				err = fmt.Errorf("Invalid notification eventTime: %s", value.String())
+				return false
+			}
+		} else if n.Type == "" {
+			n.Type = key.String()
+			n.Data = value
+		}
+		return true
+	})
+	if err != nil {
+		return n, err
+	}
+	if n.Type == "" {
+		return n, fmt.Errorf("Invalid notification, missing event content: %s", string(data))
+	}
+	return n, nil
+}
+
+// List returns each entry of the list at the given GJSON path as its own Res.
+// A single object is returned as a one-element slice.
+//
+//	for _, intf := range res.List("Cisco-IOS-XE-native:GigabitEthernet") {
+//	    println(intf.Res.Get("name").String())
+//	}
+func (res Res) List(path string) []Res {
+	result := res.Res.Get(path)
+	if !result.Exists() {
+		return nil
+	}
+	if !result.IsArray() {
+		return []Res{{Res: result}}
+	}
+	var list []Res
+	for _, entry := range result.Array() {
+		list = append(list, Res{Res: entry})
+	}
+	return list
+}
+
+// EditError holds the errors of a single YANG-Patch edit.
+type EditError struct {
+	EditId string
+	Errors []ErrorModel
+}
+
+// YangPatchErrors returns the errors of all failed YANG-Patch edits in the order reported by the device.
+func (res Res) YangPatchErrors() []EditError {
+	var editErrors []EditError
+	for _, edit := range res.YangPatchStatus.EditStatus.Edit {
+		if len(edit.Errors.Error) > 0 {
+			editErrors = append(editErrors, EditError{EditId: edit.EditId, Errors: edit.Errors.Error})
+		}
+	}
+	return editErrors
+}
+
+// all RESTCONF errors of the response including YANG-Patch edit errors
+func (res Res) allErrors() []ErrorModel {
+	errors := append([]ErrorModel{}, res.Errors.Error...)
+	for _, edit := range res.YangPatchStatus.EditStatus.Edit {
+		errors = append(errors, edit.Errors.Error...)
+	}
+	return errors
+}
+
+// parse the RESTCONF errors of a JSON or XML response body
+func parseErrorsBody(data []byte) ErrorsModel {
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '<' {
+		errors, _, _ := parseXMLErrors(data)
+		return errors
+	}
+	var errors ErrorsRootModel
+	if json.Unmarshal(data, &errors); len(errors.Errors.Error) > 0 {
+		return errors.Errors
+	}
+	var namespaceErrors ErrorsRootNamespaceModel
+	json.Unmarshal(data, &namespaceErrors)
+	return namespaceErrors.Errors
+}
+
+// parse RESTCONF errors or a YANG-Patch status from an XML response body
+func parseXMLErrors(data []byte) (ErrorsModel, YangPatchStatusModel, error) {
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		token, err := dec.Token()
+		if err != nil {
+			return ErrorsModel{}, YangPatchStatusModel{}, err
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		switch start.Name.Local {
+		case "errors":
+			var errors ErrorsModel
+			err = dec.DecodeElement(&errors, &start)
+			return errors, YangPatchStatusModel{}, err
+		case "yang-patch-status":
+			var status YangPatchStatusModel
+			err = dec.DecodeElement(&status, &start)
+			return status.Errors, status, err
+		default:
+			return ErrorsModel{}, YangPatchStatusModel{}, fmt.Errorf("unexpected root element: %s", start.Name.Local)
+		}
+	}
+}
+
+// xmlNode is an element of an XML document converted to JSON.
+type xmlNode struct {
+	space    string
+	text     strings.Builder
+	keys     []string
+	children map[string][]*xmlNode
+}
+
+// xmlToJSON converts an XML document to its JSON representation (RFC 7951).
+// Elements are qualified with a module name if their namespace differs from the parent element,
+// where the module name is derived from the last segment of the namespace URI.
+// Repeated elements are converted to arrays, elements without children to strings,
+// and empty elements to [null].
+func xmlToJSON(data []byte) (string, error) {
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	root := &xmlNode{children: make(map[string][]*xmlNode)}
+	stack := []*xmlNode{root}
+	for {
+		token, err := dec.Token()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return "", err
+		}
+		parent := stack[len(stack)-1]
+		switch t := token.(type) {
+		case xml.StartElement:
+			node := &xmlNode{space: t.Name.Space, children: make(map[string][]*xmlNode)}
+			key := t.Name.Local
+			if t.Name.Space != "" && t.Name.Space != parent.space {
+				key = xmlModuleName(t.Name.Space) + ":" + key
+			}
+			if _, ok := parent.children[key]; !ok {
+				parent.keys = append(parent.keys, key)
+			}
+			parent.children[key] = append(parent.children[key], node)
+			stack = append(stack, node)
+		case xml.EndElement:
+			stack = stack[:len(stack)-1]
+		case xml.CharData:
+			parent.text.Write(t)
+		}
+	}
+	var b strings.Builder
+	root.writeJSON(&b)
+	return b.String(), nil
+}
+
+// derive the module name from the last segment of a namespace URI
+func xmlModuleName(namespace string) string {
+	return namespace[strings.LastIndexAny(namespace, "/:")+1:]
+}
+
+func (node *xmlNode) writeJSON(b *strings.Builder) {
+	if len(node.keys) == 0 {
+		text := strings.TrimSpace(node.text.String())
+		if text == "" {
+			b.WriteString("[null]")
+			return
+		}
+		value, _ := json.Marshal(text)
+		b.Write(value)
+		return
+	}
+	b.WriteByte('{')
+	for i, key := range node.keys {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		name, _ := json.Marshal(key)
+		b.Write(name)
+		b.WriteByte(':')
+		children := node.children[key]
+		if len(children) == 1 {
+			children[0].writeJSON(b)
+			continue
+		}
+		b.WriteByte('[')
+		for j, child := range children {
+			if j > 0 {
+				b.WriteByte(',')
+			}
+			child.writeJSON(b)
+		}
+		b.WriteByte(']')
+	}
+	b.WriteByte('}')
 }
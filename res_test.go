@@ -0,0 +1,99 @@
+package restconf
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestParseNotification tests the ParseNotification function.
+func TestParseNotification(t *testing.T) {
+	n, err := ParseNotification([]byte(`{"ietf-restconf:notification":{"eventTime":"2013-12-21T00:01:00Z","example-mod:event":{"event-class":"fault"}}}`))
+	assert.NoError(t, err)
+	assert.Equal(t, "2013-12-21T00:01:00Z", n.EventTime.UTC().Format("2006-01-02T15:04:05Z07:00"))
+	assert.Equal(t, "example-mod:event", n.Type)
+	assert.Equal(t, "fault", n.Data.Get("event-class").String())
+
+	// Missing envelope
+	_, err = ParseNotification([]byte(`{"example-mod:event":{}}`))
+	assert.Error(t, err)
+}
+
+// TestPretty tests the Res::Pretty method.
+func TestPretty(t *testing.T) {
+	res := Body{}.Set("a.b", "c").Res()
+	assert.Equal(t, "{\n  \"a\": {\n    \"b\": \"c\"\n  }\n}\n", res.Pretty())
+	assert.Equal(t, `{"a":{"b":"c"}}`, res.Res.Raw)
+	assert.Equal(t, "", Res{}.Pretty())
+}
+
+// TestList tests the Res::List method.
+func TestList(t *testing.T) {
+	res := Body{}.SetRaw("a", `[{"name":"a"},{"name":"b"}]`).Res()
+	list := res.List("a")
+	assert.Len(t, list, 2)
+	assert.Equal(t, "b", list[1].Res.Get("name").String())
+
+	// Single object
+	res = Body{}.SetRaw("a", `{"name":"a"}`).Res()
+	assert.Len(t, res.List("a"), 1)
+
+	// Missing path
+	assert.Len(t, res.List("b"), 0)
+}
+
+// TestYangPatchErrors tests the Res::YangPatchErrors method.
+func TestYangPatchErrors(t *testing.T) {
+	res := Res{YangPatchStatus: YangPatchStatusModel{EditStatus: YangPatchStatusEditStatusModel{Edit: []YangPatchStatusEditStatusEditModel{
+		{EditId: "0", Ok: true},
+		{EditId: "1", Errors: ErrorsModel{Error: []ErrorModel{{ErrorTag: "invalid-value"}}}},
+	}}}}
+	editErrors := res.YangPatchErrors()
+	assert.Len(t, editErrors, 1)
+	assert.Equal(t, "1", editErrors[0].EditId)
+	assert.Equal(t, "invalid-value", editErrors[0].Errors[0].ErrorTag)
+}
+
+// TestStatusClass tests the Res::IsSuccess, Res::IsClientError and Res::IsServerError methods.
+func TestStatusClass(t *testing.T) {
+	assert.True(t, Res{StatusCode: 204}.IsSuccess())
+	assert.False(t, Res{StatusCode: 304}.IsSuccess())
+	assert.True(t, Res{StatusCode: 404}.IsClientError())
+	assert.False(t, Res{StatusCode: 500}.IsClientError())
+	assert.True(t, Res{StatusCode: 503}.IsServerError())
+	assert.False(t, Res{}.IsServerError())
+}
+
+// TestXmlToJSON tests the xmlToJSON function.
+func TestXmlToJSON(t *testing.T) {
+	json, err := xmlToJSON([]byte(`<native xmlns="http://cisco.com/ns/yang/Cisco-IOS-XE-native"><hostname>R1</hostname><interface><Loopback><name>1</name></Loopback><Loopback><name>2</name><shutdown/></Loopback></interface></native>`))
+	assert.NoError(t, err)
+	assert.Equal(t, `{"Cisco-IOS-XE-native:native":{"hostname":"R1","interface":{"Loopback":[{"name":"1"},{"name":"2","shutdown":[null]}]}}}`, json)
+
+	_, err = xmlToJSON([]byte(`<native>`))
+	assert.Error(t, err)
+}
+
+// TestParseXMLErrors tests the parseXMLErrors function.
+func TestParseXMLErrors(t *testing.T) {
+	_, status, err := parseXMLErrors([]byte(`<yang-patch-status xmlns="urn:ietf:params:xml:ns:yang:ietf-yang-patch"><patch-id>p1</patch-id><edit-status><edit><edit-id>0</edit-id><ok/></edit><edit><edit-id>1</edit-id><errors><error><error-type>application</error-type><error-tag>invalid-value</error-tag></error></errors></edit></edit-status></yang-patch-status>`))
+	assert.NoError(t, err)
+	assert.Equal(t, "p1", status.PatchId)
+	assert.True(t, bool(status.EditStatus.Edit[0].Ok))
+	assert.Equal(t, "invalid-value", status.EditStatus.Edit[1].Errors.Error[0].ErrorTag)
+}
+
+// TestErrorInfoJSON tests the ErrorModel::ErrorInfoJSON method.
+func TestErrorInfoJSON(t *testing.T) {
+	var errors ErrorsRootNamespaceModel
+	err := json.Unmarshal([]byte(`{"ietf-restconf:errors":{"error":[{"error-type":"protocol","error-tag":"unknown-element","error-info":{"bad-element":"hostnam"}},{"error-type":"application","error-tag":"lock-denied","error-info":"session 1"}]}}`), &errors)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"bad-element":"hostnam"}`, errors.Errors.Error[0].ErrorInfo)
+	assert.Equal(t, "hostnam", errors.Errors.Error[0].ErrorInfoJSON().Get("bad-element").String())
+	assert.Equal(t, "session 1", errors.Errors.Error[1].ErrorInfo)
+
+	xmlErrors, _, err := parseXMLErrors([]byte(`<errors xmlns="urn:ietf:params:xml:ns:yang:ietf-restconf"><error><error-type>application</error-type><error-tag>lock-denied</error-tag><error-info>session 1</error-info></error></errors>`))
+	assert.NoError(t, err)
+	assert.Equal(t, "session 1", xmlErrors.Error[0].ErrorInfoJSON().String())
+}